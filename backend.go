@@ -0,0 +1,426 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// FileInfo is the information of a file stored in the Backend.
+type FileInfo struct {
+	// Name is the slash separated path of the file relative to the root of the Backend.
+	// Empty name means the root itself is the file.
+	Name         string
+	Size         int64
+	LastModified time.Time
+}
+
+// Backend is the storage of the files to be synced.
+// Each Backend is rooted at the URL passed to its BackendFactory, and the files
+// are identified by the names relative to the root.
+//
+// S3 and local filesystem are supported natively. If either of the source and
+// the destination is a registered Backend, S3 and local files are listed and
+// compared as usual, and transferred through the Backend interface implemented
+// by the native functions, so that the objects are encrypted, compressed and
+// decoded as configured. The features which require the access beyond the
+// interface are not available:
+// CompareChecksum and syncing the symbolic links by SymlinksPreserve fail,
+// the downloads are not resumed by WithKeepPartial, and the mode and the owner
+// of the files are not preserved.
+type Backend interface {
+	// List calls fn for each file under the root in lexicographical order of
+	// the names, as ListObjectsV2 returns the objects.
+	// Listing should be stopped if fn returns an error.
+	List(ctx context.Context, fn func(FileInfo) error) error
+	// Stat returns the information of the file.
+	// It returns an error wrapping fs.ErrNotExist if the file doesn't exist.
+	// Stat with empty name is used to check if the root is a single file.
+	Stat(ctx context.Context, name string) (FileInfo, error)
+	// Open opens the file for reading.
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	// Put writes the file. info is the information of the source file.
+	Put(ctx context.Context, name string, r io.Reader, info FileInfo) error
+	// Delete deletes the file.
+	Delete(ctx context.Context, name string) error
+}
+
+// Copier is the optional interface of the destination Backend to copy the files
+// without transferring the contents through Open and Put, e.g. by server-side copy.
+type Copier interface {
+	// Copy copies the file from the source Backend.
+	// It should return an error wrapping errors.ErrUnsupported if the file can't be
+	// copied from the source, then the file is transferred by Open and Put.
+	Copy(ctx context.Context, source Backend, name string, info FileInfo) error
+}
+
+// BackendFactory returns the Backend rooted at the URL.
+type BackendFactory func(u *url.URL) (Backend, error)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = make(map[string]BackendFactory)
+)
+
+// RegisterBackend registers the BackendFactory of the URL scheme.
+// It panics if the scheme is "s3" or already registered.
+func RegisterBackend(scheme string, factory BackendFactory) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	if scheme == "s3" {
+		panic("s3sync: s3 backend can't be overridden")
+	}
+	if _, ok := backends[scheme]; ok {
+		panic("s3sync: backend is already registered for the scheme " + scheme)
+	}
+	backends[scheme] = factory
+}
+
+func lookupBackend(scheme string) (BackendFactory, bool) {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	f, ok := backends[scheme]
+	return f, ok
+}
+
+// backendOf returns the Backend of the path.
+// S3 and local paths are accessed by the native functions.
+func (m *Manager) backendOf(p string, u *url.URL, s3Path *s3Path) (Backend, error) {
+	if factory, ok := lookupBackend(u.Scheme); ok {
+		return factory(u)
+	}
+	if s3Path != nil {
+		return &s3Backend{m: m, bucket: s3Path.bucket, prefix: s3Path.bucketPrefix}, nil
+	}
+	return &localBackend{m: m, root: p}, nil
+}
+
+// isRegisteredBackend returns true if the Backend is not the one of S3 or local filesystem.
+func isRegisteredBackend(b Backend) bool {
+	switch b.(type) {
+	case nil, *s3Backend, *localBackend:
+		return false
+	}
+	return true
+}
+
+// joinName returns the URL of the file in the root.
+func joinName(root, name string) string {
+	if name == "" {
+		return root
+	}
+	return strings.TrimSuffix(root, "/") + "/" + name
+}
+
+// listBackendFiles returns a channel which receives the infos of the files in the Backend.
+func (m *Manager) listBackendFiles(ctx context.Context, b Backend, root string) chan *fileInfo {
+	c := make(chan *fileInfo)
+
+	go func() {
+		defer close(c)
+
+		if info, err := b.Stat(ctx, ""); err == nil {
+			// Single file was specified
+			select {
+			case c <- &fileInfo{
+				size:         info.Size,
+				lastModified: info.LastModified,
+				singleFile:   true,
+			}:
+			case <-ctx.Done():
+			}
+			return
+		}
+
+		err := b.List(ctx, func(info FileInfo) error {
+			if m.isExcluded(info.Name) {
+				return nil
+			}
+			fi := &fileInfo{
+				name:         info.Name,
+				path:         info.Name,
+				size:         info.Size,
+				lastModified: info.LastModified,
+			}
+			select {
+			case c <- fi:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil {
+			sendErrorInfoToChannel(ctx, c, &ListError{Path: root, Err: err})
+		}
+	}()
+	return c
+}
+
+// backendComparator returns compareFunc of the files in the Backends.
+// Size and modification time of the S3 objects are compared by the ones
+// recorded in the metadata if configured.
+func (m *Manager) backendComparator(ctx context.Context, p *syncPair) compareFunc {
+	if m.compareMode == CompareChecksum {
+		return func(source, dest *fileInfo) (Reason, error) {
+			return "", errors.New("comparing by checksum is not supported by the backend")
+		}
+	}
+	return m.fileComparator(ctx, p.sourceS3, p.destS3)
+}
+
+// transfer copies the file between the Backends.
+func (m *Manager) transfer(ctx context.Context, p *syncPair, file *fileInfo, progress func(int64)) error {
+	if file.symlink {
+		return errors.New("symbolic link is not supported by the backend")
+	}
+	// Empty name means the root itself is the file.
+	name := filepath.ToSlash(file.name)
+	if file.singleFile {
+		name = ""
+	}
+	info := FileInfo{Name: name, Size: file.size, LastModified: file.lastModified}
+
+	if c, ok := p.destBackend.(Copier); ok {
+		err := c.Copy(ctx, p.sourceBackend, name, info)
+		if err == nil {
+			newProgressTracker(0, progress).add(0, int(file.size))
+			m.updateFileTransferStatistics(file.size)
+			return nil
+		}
+		if !errors.Is(err, errors.ErrUnsupported) {
+			return err
+		}
+	}
+
+	r, err := p.sourceBackend.Open(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	cr := &countingReader{Reader: r, tracker: newProgressTracker(0, progress)}
+	if err := p.destBackend.Put(ctx, name, cr, info); err != nil {
+		return err
+	}
+	m.updateFileTransferStatistics(cr.n)
+	return nil
+}
+
+// deleteBackendFile deletes the file from the destination Backend.
+func (m *Manager) deleteBackendFile(ctx context.Context, p *syncPair, file *fileInfo) error {
+	if err := p.destBackend.Delete(ctx, filepath.ToSlash(file.name)); err != nil {
+		return err
+	}
+	m.incrementDeletedFiles()
+	return nil
+}
+
+// countingReader counts the bytes read from the io.Reader.
+type countingReader struct {
+	io.Reader
+	n       int64
+	tracker *progressTracker
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.tracker.add(r.n, n)
+	r.n += int64(n)
+	return n, err
+}
+
+// localBackend is the Backend of the local filesystem implemented by the native functions.
+type localBackend struct {
+	m    *Manager
+	root string
+}
+
+func (b *localBackend) filename(name string) string {
+	return filepath.Join(b.root, filepath.FromSlash(name))
+}
+
+func (b *localBackend) List(ctx context.Context, fn func(FileInfo) error) error {
+	return listNativeFiles(ctx, b.m.listLocalFiles(ctx, b.root), fn)
+}
+
+func (b *localBackend) Stat(ctx context.Context, name string) (FileInfo, error) {
+	stat, err := os.Stat(b.filename(name))
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if stat.IsDir() {
+		return FileInfo{}, fmt.Errorf("%s is a directory: %w", b.filename(name), fs.ErrNotExist)
+	}
+	return FileInfo{Name: name, Size: stat.Size(), LastModified: stat.ModTime()}, nil
+}
+
+func (b *localBackend) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	return os.Open(b.filename(name))
+}
+
+func (b *localBackend) Put(ctx context.Context, name string, r io.Reader, info FileInfo) error {
+	_, err := writeLocalFile(ctx, r, b.filename(name), info.LastModified, nil)
+	return err
+}
+
+func (b *localBackend) Delete(ctx context.Context, name string) error {
+	return os.Remove(b.filename(name))
+}
+
+// listNativeFiles calls fn for each file received from the channel.
+func listNativeFiles(ctx context.Context, c chan *fileInfo, fn func(FileInfo) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for fi := range c {
+		if fi.err != nil {
+			var le *ListError
+			if errors.As(fi.err, &le) {
+				return le.Err
+			}
+			return fi.err
+		}
+		name := filepath.ToSlash(fi.name)
+		if fi.singleFile {
+			name = ""
+		}
+		if err := fn(FileInfo{Name: name, Size: fi.size, LastModified: fi.lastModified}); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// s3Backend is the Backend of S3 implemented by the native functions.
+type s3Backend struct {
+	m      *Manager
+	bucket string
+	prefix string
+}
+
+func (b *s3Backend) key(name string) string {
+	if name == "" {
+		return b.prefix
+	}
+	return path.Join(b.prefix, name)
+}
+
+func (b *s3Backend) List(ctx context.Context, fn func(FileInfo) error) error {
+	return listNativeFiles(ctx, b.m.listS3Files(ctx, &s3Path{bucket: b.bucket, bucketPrefix: b.prefix}), fn)
+}
+
+func (b *s3Backend) Stat(ctx context.Context, name string) (FileInfo, error) {
+	key := b.key(name)
+	if key == "" || strings.HasSuffix(key, "/") {
+		return FileInfo{}, fs.ErrNotExist
+	}
+	input := &s3.HeadObjectInput{
+		Bucket: &b.bucket,
+		Key:    &key,
+	}
+	b.m.sse.headObject(input)
+	out, err := b.m.s3.HeadObject(ctx, input)
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return FileInfo{}, fmt.Errorf("%w: %w", fs.ErrNotExist, err)
+		}
+		return FileInfo{}, err
+	}
+	return FileInfo{
+		Name:         name,
+		Size:         aws.ToInt64(out.ContentLength),
+		LastModified: aws.ToTime(out.LastModified),
+	}, nil
+}
+
+// Open opens the decrypted and decompressed contents of the object if configured.
+func (b *s3Backend) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	input := &s3.GetObjectInput{
+		Bucket: &b.bucket,
+		Key:    aws.String(b.key(name)),
+	}
+	b.m.sse.getObject(input)
+	out, err := b.m.s3.GetObject(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	r, err := b.m.decodeObject(ctx, out)
+	if err != nil {
+		out.Body.Close()
+		return nil, err
+	}
+	return &decodedBody{ReadCloser: r, body: out.Body}, nil
+}
+
+// Put uploads the object as the file having the information,
+// compressed and encrypted if configured.
+// mimeReadLimit is the size of the head of the file used by mimetype to detect its type.
+const mimeReadLimit = 3072
+
+func (b *s3Backend) Put(ctx context.Context, name string, r io.Reader, info FileInfo) error {
+	file := &fileInfo{name: name, size: info.Size, lastModified: info.LastModified}
+	contentType, err := b.m.uploadContentType(func() (*mimetype.MIME, error) {
+		// The head of the body read for the detection is uploaded with the rest.
+		head := make([]byte, mimeReadLimit)
+		n, err := io.ReadFull(r, head)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, err
+		}
+		head = head[:n]
+		r = io.MultiReader(bytes.NewReader(head), r)
+		return mimetype.Detect(head), nil
+	})
+	if err != nil {
+		return err
+	}
+	return b.m.putObject(ctx, file, r, nil, contentType, &s3Path{bucket: b.bucket, bucketPrefix: b.key(name)}, nil, nil)
+}
+
+func (b *s3Backend) Delete(ctx context.Context, name string) error {
+	_, err := b.m.s3.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: &b.bucket,
+		Key:    aws.String(b.key(name)),
+	})
+	return err
+}
+
+// decodedBody closes the decoder and the body of the object.
+type decodedBody struct {
+	io.ReadCloser
+	body io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	err := b.ReadCloser.Close()
+	if bodyErr := b.body.Close(); err == nil {
+		err = bodyErr
+	}
+	return err
+}
@@ -0,0 +1,404 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/seqsense/s3sync/v2/fakes3"
+)
+
+type memFile struct {
+	data         []byte
+	lastModified time.Time
+}
+
+// memStore is the in-memory storage shared by the memBackends of the same host.
+type memStore struct {
+	mu     sync.Mutex
+	files  map[string]memFile
+	copied int
+}
+
+var (
+	memStoresMu sync.Mutex
+	memStores   = make(map[string]*memStore)
+)
+
+func getMemStore(host string) *memStore {
+	memStoresMu.Lock()
+	defer memStoresMu.Unlock()
+	s, ok := memStores[host]
+	if !ok {
+		s = &memStore{files: make(map[string]memFile)}
+		memStores[host] = s
+	}
+	return s
+}
+
+// memBackend is the Backend registered as "mem" scheme for testing.
+type memBackend struct {
+	store *memStore
+	root  string
+}
+
+func init() {
+	RegisterBackend("mem", func(u *url.URL) (Backend, error) {
+		return &memBackend{store: getMemStore(u.Host), root: strings.Trim(u.Path, "/")}, nil
+	})
+}
+
+func (b *memBackend) key(name string) string {
+	if b.root == "" {
+		return name
+	}
+	if name == "" {
+		return b.root
+	}
+	return b.root + "/" + name
+}
+
+func (b *memBackend) List(ctx context.Context, fn func(FileInfo) error) error {
+	b.store.mu.Lock()
+	var infos []FileInfo
+	for key, f := range b.store.files {
+		if b.root != "" && !strings.HasPrefix(key, b.root+"/") {
+			continue
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(key, b.root), "/")
+		infos = append(infos, FileInfo{Name: name, Size: int64(len(f.data)), LastModified: f.lastModified})
+	}
+	b.store.mu.Unlock()
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	for _, info := range infos {
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *memBackend) Stat(ctx context.Context, name string) (FileInfo, error) {
+	b.store.mu.Lock()
+	defer b.store.mu.Unlock()
+	f, ok := b.store.files[b.key(name)]
+	if !ok {
+		return FileInfo{}, fs.ErrNotExist
+	}
+	return FileInfo{Name: name, Size: int64(len(f.data)), LastModified: f.lastModified}, nil
+}
+
+func (b *memBackend) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	b.store.mu.Lock()
+	defer b.store.mu.Unlock()
+	f, ok := b.store.files[b.key(name)]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return io.NopCloser(bytes.NewReader(f.data)), nil
+}
+
+func (b *memBackend) Put(ctx context.Context, name string, r io.Reader, info FileInfo) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	b.store.mu.Lock()
+	defer b.store.mu.Unlock()
+	b.store.files[b.key(name)] = memFile{data: data, lastModified: info.LastModified}
+	return nil
+}
+
+func (b *memBackend) Delete(ctx context.Context, name string) error {
+	b.store.mu.Lock()
+	defer b.store.mu.Unlock()
+	delete(b.store.files, b.key(name))
+	return nil
+}
+
+func (b *memBackend) Copy(ctx context.Context, source Backend, name string, info FileInfo) error {
+	src, ok := source.(*memBackend)
+	if !ok || src.store != b.store {
+		return fmt.Errorf("copy from %T: %w", source, errors.ErrUnsupported)
+	}
+	b.store.mu.Lock()
+	defer b.store.mu.Unlock()
+	b.store.files[b.key(name)] = b.store.files[src.key(name)]
+	b.store.copied++
+	return nil
+}
+
+func (s *memStore) keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []string
+	for key := range s.files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestBackend(t *testing.T) {
+	t0 := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("LocalToBackend", func(t *testing.T) {
+		temp := t.TempDir()
+		for _, file := range []string{"test1", "foo/test2"} {
+			filename := filepath.Join(temp, file)
+			if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filename, []byte(file), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(filename, t0, t0); err != nil {
+				t.Fatal(err)
+			}
+		}
+		store := getMemStore("local-to-backend")
+		store.files["dir/dest_only"] = memFile{data: []byte("test")}
+
+		m := New(getSession(), WithDelete())
+		if err := m.Sync(context.Background(), temp, "mem://local-to-backend/dir"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if keys := store.keys(); !reflect.DeepEqual([]string{"dir/foo/test2", "dir/test1"}, keys) {
+			t.Errorf("Unexpected files: %v", keys)
+		}
+		if f := store.files["dir/foo/test2"]; string(f.data) != "foo/test2" || !f.lastModified.Equal(t0) {
+			t.Errorf("Unexpected file: %s, %v", string(f.data), f.lastModified)
+		}
+		stats := m.GetStatistics()
+		if stats.Files != 2 || stats.Bytes != 14 || stats.DeletedFiles != 1 {
+			t.Errorf("Unexpected statistics: files %d, bytes %d, deleted %d", stats.Files, stats.Bytes, stats.DeletedFiles)
+		}
+
+		// Second sync doesn't transfer anything.
+		m = New(getSession())
+		if err := m.Sync(context.Background(), temp, "mem://local-to-backend/dir"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if n := m.GetStatistics().Files; n != 0 {
+			t.Errorf("Up to date files should not be transferred, but %d files are transferred", n)
+		}
+	})
+	t.Run("BackendToLocal", func(t *testing.T) {
+		store := getMemStore("backend-to-local")
+		store.files["test1"] = memFile{data: []byte("test1"), lastModified: t0}
+		store.files["foo/test2"] = memFile{data: []byte("test2"), lastModified: t0}
+
+		temp := t.TempDir()
+		if err := New(getSession()).Sync(context.Background(), "mem://backend-to-local", temp); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		for _, file := range []string{"test1", "foo/test2"} {
+			stat, err := os.Stat(filepath.Join(temp, file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stat.Size() != 5 || !stat.ModTime().Equal(t0) {
+				t.Errorf("Unexpected file %s: size %d, mtime %v", file, stat.Size(), stat.ModTime())
+			}
+		}
+	})
+	t.Run("Copier", func(t *testing.T) {
+		store := getMemStore("copier")
+		store.files["src/test1"] = memFile{data: []byte("test1"), lastModified: t0}
+
+		m := New(getSession())
+		if err := m.Sync(context.Background(), "mem://copier/src", "mem://copier/dest"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if store.copied != 1 {
+			t.Errorf("File should be copied by Copier, but copied %d times", store.copied)
+		}
+		if keys := store.keys(); !reflect.DeepEqual([]string{"dest/test1", "src/test1"}, keys) {
+			t.Errorf("Unexpected files: %v", keys)
+		}
+		if stats := m.GetStatistics(); stats.Files != 1 || stats.Bytes != 5 {
+			t.Errorf("Unexpected statistics: files %d, bytes %d", stats.Files, stats.Bytes)
+		}
+	})
+	t.Run("SingleFile", func(t *testing.T) {
+		store := getMemStore("single-file")
+		store.files["foo/test1"] = memFile{data: []byte("test1"), lastModified: t0}
+
+		temp := t.TempDir()
+		if err := New(getSession()).Sync(context.Background(), "mem://single-file/foo/test1", filepath.Join(temp, "bar")); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		fileHasSize(t, filepath.Join(temp, "bar"), 5)
+	})
+	t.Run("Plan", func(t *testing.T) {
+		store := getMemStore("plan")
+		store.files["test1"] = memFile{data: []byte("test1"), lastModified: t0}
+
+		temp := t.TempDir()
+		plan, err := New(getSession()).Plan(context.Background(), "mem://plan", temp)
+		if err != nil {
+			t.Fatal("Plan should be successful", err)
+		}
+		expected := []Operation{{
+			Type:         OperationCopy,
+			Source:       "mem://plan/test1",
+			Dest:         filepath.Join(temp, "test1"),
+			Size:         5,
+			LastModified: t0,
+			Reason:       ReasonMissing,
+		}}
+		for i := range plan.Operations {
+			plan.Operations[i].op = nil
+		}
+		if !reflect.DeepEqual(expected, plan.Operations) {
+			t.Errorf("Expected %+v, got %+v", expected, plan.Operations)
+		}
+	})
+	t.Run("ChecksumNotSupported", func(t *testing.T) {
+		store := getMemStore("checksum")
+		store.files["test1"] = memFile{data: []byte("test1"), lastModified: t0}
+
+		err := New(getSession(), WithCompareMode(CompareChecksum)).Sync(context.Background(), "mem://checksum", "mem://checksum")
+		var fe *FileError
+		if !errors.As(err, &fe) {
+			t.Errorf("FileError is expected, got %v", err)
+		}
+	})
+	t.Run("Compression", func(t *testing.T) {
+		store := getMemStore("compression")
+		store.files["test1"] = memFile{data: bytes.Repeat([]byte("test1"), 100), lastModified: t0}
+
+		c := fakes3.New("bucket")
+		if err := NewWithClient(c, WithCompression(CompressionGzip)).Sync(context.Background(), "mem://compression", "s3://bucket"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		out, err := c.HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String("test1")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if aws.ToString(out.ContentEncoding) != "gzip" || aws.ToInt64(out.ContentLength) >= 500 {
+			t.Errorf("Object should be compressed, got encoding %q, size %d", aws.ToString(out.ContentEncoding), aws.ToInt64(out.ContentLength))
+		}
+
+		// Compressed object is compared by the original size.
+		m := NewWithClient(c, WithCompression(CompressionGzip))
+		if err := m.Sync(context.Background(), "mem://compression", "s3://bucket"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if n := m.GetStatistics().Files; n != 0 {
+			t.Errorf("Up to date files should not be transferred, but %d files are transferred", n)
+		}
+
+		if err := NewWithClient(c, WithDecompression()).Sync(context.Background(), "s3://bucket", "mem://compression/dest"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if f := store.files["dest/test1"]; !bytes.Equal(store.files["test1"].data, f.data) {
+			t.Errorf("Object should be decompressed, got %d bytes", len(f.data))
+		}
+	})
+	t.Run("GuessMimeType", func(t *testing.T) {
+		store := getMemStore("mime")
+		store.files["test.json"] = memFile{data: []byte(`{"test": 1}`), lastModified: t0}
+
+		c := fakes3.New("bucket")
+		if err := NewWithClient(c).Sync(context.Background(), "mem://mime", "s3://bucket"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		out, err := c.GetObject(context.Background(), &s3.GetObjectInput{Bucket: aws.String("bucket"), Key: aws.String("test.json")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer out.Body.Close()
+		if ct := aws.ToString(out.ContentType); ct != "application/json" {
+			t.Errorf("Expected application/json, got %q", ct)
+		}
+		data, err := io.ReadAll(out.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != `{"test": 1}` {
+			t.Errorf("Object body should be uploaded entirely, got %q", data)
+		}
+	})
+	t.Run("StateCache", func(t *testing.T) {
+		store := getMemStore("state-cache")
+		store.files["test1"] = memFile{data: []byte("test1"), lastModified: t0}
+
+		temp := t.TempDir()
+		stateDir := t.TempDir()
+		if err := New(getSession(), WithStateCache(stateDir, StateTrust)).Sync(context.Background(), "mem://state-cache", temp); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if err := os.Remove(filepath.Join(temp, "test1")); err != nil {
+			t.Fatal(err)
+		}
+
+		// Dest files are listed from the state cache.
+		m := New(getSession(), WithStateCache(stateDir, StateTrust))
+		if err := m.Sync(context.Background(), "mem://state-cache", temp); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if n := m.GetStatistics().Files; n != 0 {
+			t.Errorf("Files recorded in the state cache should not be transferred, but %d files are transferred", n)
+		}
+	})
+	t.Run("Symlink", func(t *testing.T) {
+		temp := t.TempDir()
+		if err := os.WriteFile(filepath.Join(temp, "test1"), []byte("test1"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink("test1", filepath.Join(temp, "link")); err != nil {
+			t.Fatal(err)
+		}
+
+		err := New(getSession(), WithSymlinks(SymlinksPreserve)).Sync(context.Background(), temp, "mem://symlink")
+		var fe *FileError
+		if !errors.As(err, &fe) {
+			t.Errorf("FileError is expected, got %v", err)
+		}
+		if keys := getMemStore("symlink").keys(); !reflect.DeepEqual([]string{"test1"}, keys) {
+			t.Errorf("Unexpected files: %v", keys)
+		}
+	})
+}
+
+func TestRegisterBackend(t *testing.T) {
+	factory := func(u *url.URL) (Backend, error) { return nil, nil }
+	for _, scheme := range []string{"s3", "mem"} {
+		scheme := scheme
+		t.Run(scheme, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Registering %s backend should panic", scheme)
+				}
+			}()
+			RegisterBackend(scheme, factory)
+		})
+	}
+}
@@ -0,0 +1,333 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// CompareMode specifies how to detect the files to be synced.
+type CompareMode int
+
+const (
+	// CompareSizeAndModTime syncs the files having different size or
+	// newer modification time than the destination. This is the default.
+	CompareSizeAndModTime CompareMode = iota
+	// CompareChecksum syncs the files having different size or contents,
+	// regardless of the modification time.
+	//
+	// The contents are compared by the S3 additional checksum (SHA-256, CRC32C
+	// or CRC32) if the object has it, otherwise by the ETag.
+	// Multipart ETags and composite checksums are recomputed using the part size
+	// of the uploader.
+	// Note that the ETag of the objects encrypted by SSE-KMS or SSE-C is not
+	// the MD5 digest of the contents and such objects are always synced.
+	CompareChecksum
+)
+
+// compareFunc returns the reason why the dest file has to be updated by the source file.
+// Empty Reason is returned if the dest file is up to date.
+type compareFunc func(source, dest *fileInfo) (Reason, error)
+
+// compareBySizeAndModTime requires the dest to have the same size as the source
+// and not to be older than the source.
+func compareBySizeAndModTime(source, dest *fileInfo) (Reason, error) {
+	switch {
+	case source.size != dest.size:
+		return ReasonSizeDiffers, nil
+	case source.lastModified.After(dest.lastModified):
+		return ReasonNewer, nil
+	}
+	return "", nil
+}
+
+// fileComparator returns compareFunc for the configured CompareMode.
+// nil path means the local filesystem.
+func (m *Manager) fileComparator(ctx context.Context, sourcePath, destPath *s3Path) compareFunc {
+	if !m.usesFileMetadata() || (sourcePath == nil && destPath == nil) {
+		return m.contentComparator(ctx, sourcePath, destPath)
+	}
+	// Size and modification time of the encrypted or compressed objects are
+	// compared by the ones of the source file recorded in the metadata.
+	return func(source, dest *fileInfo) (Reason, error) {
+		if m.compareMode == CompareChecksum {
+			return "", errors.New("comparing by checksum is not supported with client-side encryption or compression")
+		}
+		if sourcePath != nil {
+			if err := m.headFileMetadata(ctx, sourcePath.bucket, source); err != nil {
+				return "", err
+			}
+		}
+		if destPath != nil {
+			if err := m.headFileMetadata(ctx, destPath.bucket, dest); err != nil {
+				return "", err
+			}
+		}
+		return compareBySizeAndModTime(source, dest)
+	}
+}
+
+// contentComparator returns compareFunc for the configured CompareMode.
+func (m *Manager) contentComparator(ctx context.Context, sourcePath, destPath *s3Path) compareFunc {
+	if m.compareMode != CompareChecksum {
+		return compareBySizeAndModTime
+	}
+	return func(source, dest *fileInfo) (Reason, error) {
+		if source.size != dest.size {
+			return ReasonSizeDiffers, nil
+		}
+		var same bool
+		var err error
+		switch {
+		case sourcePath != nil && destPath != nil:
+			same, err = m.isSameObject(ctx, source, sourcePath.bucket, dest, destPath.bucket)
+		case sourcePath != nil:
+			same, err = m.isSameFileAndObject(ctx, dest.path, source, sourcePath.bucket)
+		case destPath != nil:
+			same, err = m.isSameFileAndObject(ctx, source.path, dest, destPath.bucket)
+		default:
+			same, err = isSameFile(source.path, dest.path)
+		}
+		if err != nil || same {
+			return "", err
+		}
+		return ReasonContentDiffers, nil
+	}
+}
+
+// checksumAlgorithms is the list of the supported S3 additional checksum algorithms
+// in order of preference.
+var checksumAlgorithms = []types.ChecksumAlgorithm{
+	types.ChecksumAlgorithmSha256,
+	types.ChecksumAlgorithmCrc32c,
+	types.ChecksumAlgorithmCrc32,
+}
+
+func hasChecksumAlgorithm(file *fileInfo, alg types.ChecksumAlgorithm) bool {
+	for _, a := range file.checksumAlgorithm {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
+// isSameObject compares the contents of two S3 objects.
+func (m *Manager) isSameObject(ctx context.Context, source *fileInfo, sourceBucket string, dest *fileInfo, destBucket string) (bool, error) {
+	if source.etag != "" && source.etag == dest.etag {
+		return true, nil
+	}
+	if source.checksumType != "" && dest.checksumType != "" && source.checksumType != dest.checksumType {
+		// Composite and full object checksums of the same contents differ.
+		return false, nil
+	}
+	for _, alg := range checksumAlgorithms {
+		if !hasChecksumAlgorithm(source, alg) || !hasChecksumAlgorithm(dest, alg) {
+			continue
+		}
+		sourceSum, err := m.objectChecksum(ctx, sourceBucket, source, alg)
+		if err != nil {
+			return false, err
+		}
+		destSum, err := m.objectChecksum(ctx, destBucket, dest, alg)
+		if err != nil {
+			return false, err
+		}
+		if sourceSum != "" && destSum != "" {
+			return sourceSum == destSum, nil
+		}
+	}
+	return false, nil
+}
+
+// isSameFileAndObject compares the contents of the local file and the S3 object.
+func (m *Manager) isSameFileAndObject(ctx context.Context, filename string, object *fileInfo, bucket string) (bool, error) {
+	for _, alg := range checksumAlgorithms {
+		if !hasChecksumAlgorithm(object, alg) {
+			continue
+		}
+		remote, err := m.objectChecksum(ctx, bucket, object, alg)
+		if err != nil {
+			return false, err
+		}
+		if remote == "" {
+			continue
+		}
+		local, err := fileDigest(filename, checksumHash(alg), m.uploadPartSize(object.size), numParts(remote), base64.StdEncoding.EncodeToString)
+		if err != nil {
+			return false, err
+		}
+		return local == remote, nil
+	}
+	if object.etag == "" {
+		return false, nil
+	}
+	local, err := fileDigest(filename, md5.New, m.uploadPartSize(object.size), numParts(object.etag), hex.EncodeToString)
+	if err != nil {
+		return false, err
+	}
+	return local == object.etag, nil
+}
+
+// isSameFile compares the contents of two local files.
+func isSameFile(filename1, filename2 string) (bool, error) {
+	digest1, err := fileDigest(filename1, sha256.New, 0, 0, hex.EncodeToString)
+	if err != nil {
+		return false, err
+	}
+	digest2, err := fileDigest(filename2, sha256.New, 0, 0, hex.EncodeToString)
+	if err != nil {
+		return false, err
+	}
+	return digest1 == digest2, nil
+}
+
+// objectChecksum returns the base64 encoded additional checksum of the S3 object.
+func (m *Manager) objectChecksum(ctx context.Context, bucket string, file *fileInfo, alg types.ChecksumAlgorithm) (string, error) {
+	input := &s3.HeadObjectInput{
+		Bucket:       &bucket,
+		Key:          aws.String(file.objectKey()),
+		ChecksumMode: types.ChecksumModeEnabled,
+	}
+	m.sse.headObject(input)
+	out, err := m.s3.HeadObject(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	switch alg {
+	case types.ChecksumAlgorithmSha256:
+		return aws.ToString(out.ChecksumSHA256), nil
+	case types.ChecksumAlgorithmCrc32c:
+		return aws.ToString(out.ChecksumCRC32C), nil
+	case types.ChecksumAlgorithmCrc32:
+		return aws.ToString(out.ChecksumCRC32), nil
+	}
+	return "", nil
+}
+
+// objectKey returns the S3 object key of the file listed by listS3Files.
+func (f *fileInfo) objectKey() string {
+	if f.singleFile {
+		return path.Join(filepath.ToSlash(f.path), f.name)
+	}
+	return f.path
+}
+
+func checksumHash(alg types.ChecksumAlgorithm) func() hash.Hash {
+	switch alg {
+	case types.ChecksumAlgorithmCrc32c:
+		return func() hash.Hash {
+			return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+		}
+	case types.ChecksumAlgorithmCrc32:
+		return func() hash.Hash {
+			return crc32.NewIEEE()
+		}
+	default:
+		return sha256.New
+	}
+}
+
+// uploadPartSize returns the part size used by the uploader to upload the file of the given size.
+func (m *Manager) uploadPartSize(size int64) int64 {
+	u := manager.NewUploader(m.s3, m.uploaderOpts...)
+	partSize := u.PartSize
+	if partSize < manager.MinUploadPartSize {
+		partSize = manager.MinUploadPartSize
+	}
+	maxParts := int64(u.MaxUploadParts)
+	if maxParts <= 0 {
+		maxParts = int64(manager.MaxUploadParts)
+	}
+	if size/partSize >= maxParts {
+		// Same as the adjustment made by the uploader.
+		partSize = size/maxParts + 1
+	}
+	return partSize
+}
+
+// numParts returns the number of the parts of the multipart ETag or composite checksum.
+// It returns 0 if the value is calculated from the whole contents.
+func numParts(digest string) int {
+	i := strings.LastIndex(digest, "-")
+	if i < 0 {
+		return 0
+	}
+	n, err := strconv.Atoi(digest[i+1:])
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// fileDigest calculates the digest of the file in the same manner as S3.
+// If parts is 0, it returns the encoded digest of the whole contents.
+// Otherwise, it returns the encoded digest of the concatenated digests of
+// each partSize bytes, suffixed by the number of the parts.
+// Empty string is returned if the file doesn't have the given number of the parts.
+func fileDigest(filename string, newHash func() hash.Hash, partSize int64, parts int, encode func([]byte) string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if parts == 0 {
+		h := newHash()
+		if _, err := io.Copy(h, f); err != nil {
+			return "", err
+		}
+		return encode(h.Sum(nil)), nil
+	}
+
+	composite := newHash()
+	var n int
+	for {
+		h := newHash()
+		written, err := io.CopyN(h, f, partSize)
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		if written == 0 {
+			break
+		}
+		composite.Write(h.Sum(nil))
+		n++
+		if err == io.EOF {
+			break
+		}
+	}
+	if n != parts {
+		return "", nil
+	}
+	return fmt.Sprintf("%s-%d", encode(composite.Sum(nil)), n), nil
+}
@@ -0,0 +1,193 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/seqsense/s3sync/v2/fakes3"
+)
+
+func TestCompareBySizeAndModTime(t *testing.T) {
+	t0 := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Second)
+
+	testCases := map[string]struct {
+		source, dest *fileInfo
+		expected     Reason
+	}{
+		"Same": {
+			source:   &fileInfo{size: 10, lastModified: t0},
+			dest:     &fileInfo{size: 10, lastModified: t0},
+			expected: "",
+		},
+		"DestNewer": {
+			source:   &fileInfo{size: 10, lastModified: t0},
+			dest:     &fileInfo{size: 10, lastModified: t1},
+			expected: "",
+		},
+		"SourceNewer": {
+			source:   &fileInfo{size: 10, lastModified: t1},
+			dest:     &fileInfo{size: 10, lastModified: t0},
+			expected: ReasonNewer,
+		},
+		"SizeDiffers": {
+			source:   &fileInfo{size: 10, lastModified: t0},
+			dest:     &fileInfo{size: 11, lastModified: t1},
+			expected: ReasonSizeDiffers,
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			reason, err := compareBySizeAndModTime(tt.source, tt.dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reason != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, reason)
+			}
+		})
+	}
+}
+
+func TestIsSameObject(t *testing.T) {
+	c := fakes3.New("bucket")
+	for _, key := range []string{"a", "b"} {
+		_, err := c.PutObject(context.Background(), &s3.PutObjectInput{
+			Bucket:            aws.String("bucket"),
+			Key:               aws.String(key),
+			Body:              bytes.NewReader([]byte("data")),
+			ChecksumAlgorithm: types.ChecksumAlgorithmCrc32,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	crc32 := []types.ChecksumAlgorithm{types.ChecksumAlgorithmCrc32}
+	m := NewWithClient(c)
+
+	testCases := map[string]struct {
+		sourceType, destType types.ChecksumType
+		expected             bool
+	}{
+		"SameType": {
+			sourceType: types.ChecksumTypeFullObject,
+			destType:   types.ChecksumTypeFullObject,
+			expected:   true,
+		},
+		"UnknownType": {
+			sourceType: types.ChecksumTypeFullObject,
+			expected:   true,
+		},
+		"TypeDiffers": {
+			sourceType: types.ChecksumTypeFullObject,
+			destType:   types.ChecksumTypeComposite,
+			expected:   false,
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			// ETags differ so that the checksums are compared.
+			source := &fileInfo{path: "a", etag: "source", checksumAlgorithm: crc32, checksumType: tt.sourceType}
+			dest := &fileInfo{path: "b", etag: "dest", checksumAlgorithm: crc32, checksumType: tt.destType}
+			same, err := m.isSameObject(context.Background(), source, "bucket", dest, "bucket")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if same != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, same)
+			}
+		})
+	}
+}
+
+func TestNumParts(t *testing.T) {
+	testCases := map[string]int{
+		"d41d8cd98f00b204e9800998ecf8427e":                0,
+		"d41d8cd98f00b204e9800998ecf8427e-3":              3,
+		"47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=-12": 12,
+		"foo-bar": 0,
+	}
+	for digest, expected := range testCases {
+		if n := numParts(digest); n != expected {
+			t.Errorf("Expected %d parts for %s, got %d", expected, digest, n)
+		}
+	}
+}
+
+func TestFileDigest(t *testing.T) {
+	temp := t.TempDir()
+	filename := filepath.Join(temp, "file")
+	data := []byte("0123456789a")
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal("Failed to write", err)
+	}
+
+	t.Run("Whole", func(t *testing.T) {
+		sum := md5.Sum(data)
+		expected := hex.EncodeToString(sum[:])
+
+		digest, err := fileDigest(filename, md5.New, 4, 0, hex.EncodeToString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if digest != expected {
+			t.Errorf("Expected %s, got %s", expected, digest)
+		}
+	})
+	t.Run("Multipart", func(t *testing.T) {
+		var sums []byte
+		for _, p := range [][]byte{data[0:4], data[4:8], data[8:]} {
+			sum := sha256.Sum256(p)
+			sums = append(sums, sum[:]...)
+		}
+		sum := sha256.Sum256(sums)
+		expected := base64.StdEncoding.EncodeToString(sum[:]) + "-3"
+
+		digest, err := fileDigest(filename, sha256.New, 4, 3, base64.StdEncoding.EncodeToString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if digest != expected {
+			t.Errorf("Expected %s, got %s", expected, digest)
+		}
+	})
+	t.Run("PartsMismatch", func(t *testing.T) {
+		digest, err := fileDigest(filename, md5.New, 4, 2, hex.EncodeToString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if digest != "" {
+			t.Errorf("Expected empty digest, got %s", digest)
+		}
+	})
+	t.Run("NotExist", func(t *testing.T) {
+		if _, err := fileDigest(filepath.Join(temp, "not_exist"), md5.New, 4, 0, hex.EncodeToString); err == nil {
+			t.Error("Expected error")
+		}
+	})
+}
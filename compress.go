@@ -0,0 +1,150 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"context"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is the algorithm to compress the uploaded files.
+type Compression string
+
+const (
+	// CompressionNone uploads the files as is.
+	CompressionNone Compression = ""
+	// CompressionGzip compresses the files by gzip.
+	CompressionGzip Compression = "gzip"
+	// CompressionZstd compresses the files by Zstandard.
+	CompressionZstd Compression = "zstd"
+)
+
+// metaCompression is the metadata key of the compression algorithm.
+// It is recorded in addition to Content-Encoding since Content-Encoding of
+// the encrypted objects is not set.
+const metaCompression = "s3sync-compression"
+
+// compress returns the reader of the compressed contents.
+// The returned reader must be closed to stop compressing.
+func compress(r io.Reader, c Compression) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		var w io.WriteCloser
+		var err error
+		switch c {
+		case CompressionGzip:
+			w = gzip.NewWriter(pw)
+		case CompressionZstd:
+			w, err = zstd.NewWriter(pw)
+		}
+		if err == nil {
+			_, err = io.Copy(w, r)
+			if closeErr := w.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// decompress returns the reader of the decompressed contents.
+// The contents compressed by unsupported algorithm are returned as is.
+func decompress(r io.Reader, c Compression) (io.ReadCloser, error) {
+	switch c {
+	case CompressionGzip:
+		return gzip.NewReader(r)
+	case CompressionZstd:
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	}
+	return io.NopCloser(r), nil
+}
+
+// usesFileMetadata returns true if the object contents differ from the source
+// file and the source file attributes are recorded in the metadata.
+func (m *Manager) usesFileMetadata() bool {
+	return m.keyProvider != nil || m.compression != CompressionNone || m.decompress || m.preserveMtime
+}
+
+// preservesAttributes returns true if any of the source file attributes are preserved.
+func (m *Manager) preservesAttributes() bool {
+	return m.preserveMtime || m.preserveMode || m.preserveOwner
+}
+
+// downloadDecoded downloads the object and writes the decrypted and
+// decompressed contents to the file.
+func (m *Manager) downloadDecoded(ctx context.Context, file *fileInfo, bucket, key, targetFilename string, progress func(int64)) error {
+	input := &s3.GetObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+	}
+	m.sse.getObject(input)
+	out, err := m.s3.GetObject(ctx, input)
+	if err != nil {
+		return err
+	}
+	defer out.Body.Close()
+
+	source := *file
+	source.lastModified = aws.ToTime(out.LastModified)
+	applyFileMetadata(&source, out.Metadata)
+	if m.symlinks == SymlinksPreserve && source.symlink {
+		// Symbolic links are neither compressed nor encrypted.
+		return m.writeSymlink(out.Body, targetFilename, &source, progress)
+	}
+
+	r, err := m.decodeObject(ctx, out)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	written, err := writeLocalFile(ctx, r, targetFilename, source.lastModified, newProgressTracker(0, progress))
+	if err != nil {
+		return err
+	}
+	if err := m.restoreFileAttributes(targetFilename, &source); err != nil {
+		return err
+	}
+	m.updateFileTransferStatistics(written)
+	return nil
+}
+
+// decodeObject returns the reader of the decrypted and decompressed contents
+// of the object. Closing the returned reader doesn't close the body.
+func (m *Manager) decodeObject(ctx context.Context, out *s3.GetObjectOutput) (io.ReadCloser, error) {
+	var r io.Reader = out.Body
+	if m.keyProvider != nil {
+		var err error
+		if r, err = m.decrypt(ctx, r, out.Metadata); err != nil {
+			return nil, err
+		}
+	}
+	if !m.decompress {
+		return io.NopCloser(r), nil
+	}
+	c := Compression(out.Metadata[metaCompression])
+	if c == CompressionNone {
+		c = Compression(aws.ToString(out.ContentEncoding))
+	}
+	return decompress(r, c)
+}
@@ -0,0 +1,78 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestCompress(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+
+	for _, c := range []Compression{CompressionGzip, CompressionZstd} {
+		c := c
+		t.Run(string(c), func(t *testing.T) {
+			r := compress(bytes.NewReader(data), c)
+			defer r.Close()
+			compressed, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(compressed) >= len(data) {
+				t.Errorf("Data is not compressed: %d bytes", len(compressed))
+			}
+
+			d, err := decompress(bytes.NewReader(compressed), c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer d.Close()
+			decompressed, err := io.ReadAll(d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, decompressed) {
+				t.Error("Decompressed data differs")
+			}
+		})
+	}
+
+	t.Run("Unsupported", func(t *testing.T) {
+		d, err := decompress(bytes.NewReader(data), "br")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := io.ReadAll(d); !bytes.Equal(data, b) {
+			t.Error("Data compressed by unsupported algorithm should be returned as is")
+		}
+	})
+	t.Run("ReadError", func(t *testing.T) {
+		errRead := errors.New("read error")
+		r := compress(io.MultiReader(bytes.NewReader(data), &errorReader{err: errRead}), CompressionGzip)
+		defer r.Close()
+		if _, err := io.ReadAll(r); !errors.Is(err, errRead) {
+			t.Errorf("Expected %v, got %v", errRead, err)
+		}
+	})
+}
+
+type errorReader struct {
+	err error
+}
+
+func (r *errorReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,222 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Metadata keys of the client-side encryption.
+const (
+	metaCSEKey   = "s3sync-cse-key"
+	metaCSENonce = "s3sync-cse-nonce"
+)
+
+// cseChunkSize is the size of the plaintext chunks encrypted individually.
+const cseChunkSize = 64 * 1024
+
+// KeyProvider provides the data keys of the client-side encryption.
+// Each object is encrypted by its own data key, and the data key encrypted by
+// the KeyProvider is stored in the object metadata.
+// It can be implemented by KMS GenerateDataKey and Decrypt APIs.
+type KeyProvider interface {
+	// GenerateDataKey returns a new 256-bit data key and its encrypted form.
+	GenerateDataKey(ctx context.Context) (key, encryptedKey []byte, err error)
+	// DecryptDataKey decrypts the data key encrypted by GenerateDataKey.
+	DecryptDataKey(ctx context.Context, encryptedKey []byte) ([]byte, error)
+}
+
+// aesKeyProvider encrypts the data keys by AES-256-GCM with the master key.
+type aesKeyProvider struct {
+	aead cipher.AEAD
+}
+
+// NewAESKeyProvider returns the KeyProvider which encrypts the data keys by
+// AES-256-GCM with the 256-bit master key.
+func NewAESKeyProvider(masterKey []byte) (KeyProvider, error) {
+	if len(masterKey) != 32 {
+		return nil, errors.New("master key must be 32 bytes")
+	}
+	aead, err := newAEAD(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	return &aesKeyProvider{aead: aead}, nil
+}
+
+func (p *aesKeyProvider) GenerateDataKey(ctx context.Context) ([]byte, []byte, error) {
+	key := make([]byte, 32)
+	nonce := make([]byte, p.aead.NonceSize())
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, err
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	return key, p.aead.Seal(nonce, nonce, key, nil), nil
+}
+
+func (p *aesKeyProvider) DecryptDataKey(ctx context.Context, encryptedKey []byte) ([]byte, error) {
+	n := p.aead.NonceSize()
+	if len(encryptedKey) < n {
+		return nil, errors.New("encrypted data key is too short")
+	}
+	return p.aead.Open(nil, encryptedKey[:n], encryptedKey[n:], nil)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt returns the reader of the encrypted contents and sets the data key
+// and the nonce to the metadata.
+func (m *Manager) encrypt(ctx context.Context, r io.Reader, metadata map[string]string) (io.Reader, error) {
+	key, encryptedKey, err := m.keyProvider.GenerateDataKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	metadata[metaCSEKey] = base64.StdEncoding.EncodeToString(encryptedKey)
+	metadata[metaCSENonce] = base64.StdEncoding.EncodeToString(nonce)
+	return &chunkCipherReader{
+		r:     bufio.NewReaderSize(r, cseChunkSize),
+		aead:  aead,
+		nonce: nonce,
+		in:    make([]byte, cseChunkSize),
+	}, nil
+}
+
+// decrypt returns the reader of the decrypted contents of the object.
+// The object not encrypted by s3sync is returned as is.
+func (m *Manager) decrypt(ctx context.Context, r io.Reader, metadata map[string]string) (io.Reader, error) {
+	encodedKey, ok := metadata[metaCSEKey]
+	if !ok {
+		return r, nil
+	}
+	encryptedKey, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted data key: %w", err)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(metadata[metaCSENonce])
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce: %w", err)
+	}
+	key, err := m.keyProvider.DecryptDataKey(ctx, encryptedKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid nonce size")
+	}
+	return &chunkCipherReader{
+		r:       bufio.NewReaderSize(r, cseChunkSize+aead.Overhead()),
+		aead:    aead,
+		nonce:   nonce,
+		in:      make([]byte, cseChunkSize+aead.Overhead()),
+		decrypt: true,
+	}, nil
+}
+
+// chunkCipherReader encrypts or decrypts the stream by the chunks.
+// Each chunk is sealed with the nonce derived from the chunk index and the
+// additional data marking the last chunk, so that reordering and truncation
+// of the chunks are detected.
+type chunkCipherReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	nonce   []byte
+	decrypt bool
+
+	in    []byte
+	buf   []byte
+	out   []byte
+	index uint64
+	done  bool
+}
+
+func (c *chunkCipherReader) Read(p []byte) (int, error) {
+	for len(c.out) == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+		if err := c.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, c.out)
+	c.out = c.out[n:]
+	return n, nil
+}
+
+func (c *chunkCipherReader) next() error {
+	n, err := io.ReadFull(c.r, c.in)
+	last := false
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		last = true
+	case err != nil:
+		return err
+	default:
+		if _, err := c.r.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	nonce := make([]byte, len(c.nonce))
+	copy(nonce, c.nonce)
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], binary.BigEndian.Uint64(nonce[len(nonce)-8:])^c.index)
+	ad := []byte{0}
+	if last {
+		ad[0] = 1
+	}
+
+	if c.decrypt {
+		buf, err := c.aead.Open(c.buf[:0], nonce, c.in[:n], ad)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt chunk %d: %w", c.index, err)
+		}
+		c.buf = buf
+	} else {
+		c.buf = c.aead.Seal(c.buf[:0], nonce, c.in[:n], ad)
+	}
+	c.out = c.buf
+	c.index++
+	c.done = last
+	return nil
+}
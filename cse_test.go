@@ -0,0 +1,129 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func newTestKeyProvider(t *testing.T) KeyProvider {
+	t.Helper()
+	p, err := NewAESKeyProvider(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestAESKeyProvider(t *testing.T) {
+	if _, err := NewAESKeyProvider(make([]byte, 16)); err == nil {
+		t.Error("Master key shorter than 32 bytes should be rejected")
+	}
+
+	p := newTestKeyProvider(t)
+	key, encryptedKey, err := p.GenerateDataKey(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != 32 {
+		t.Errorf("Expected 32 bytes key, got %d bytes", len(key))
+	}
+	decrypted, err := p.DecryptDataKey(context.Background(), encryptedKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key, decrypted) {
+		t.Error("Decrypted key differs")
+	}
+
+	other, err := NewAESKeyProvider(bytes.Repeat([]byte{2}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.DecryptDataKey(context.Background(), encryptedKey); err == nil {
+		t.Error("Data key should not be decrypted by the other master key")
+	}
+}
+
+func TestClientSideEncryption(t *testing.T) {
+	m := New(aws.Config{}, WithClientSideEncryption(newTestKeyProvider(t)))
+
+	encrypt := func(t *testing.T, data []byte) ([]byte, map[string]string) {
+		t.Helper()
+		metadata := make(map[string]string)
+		r, err := m.encrypt(context.Background(), bytes.NewReader(data), metadata)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encrypted, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return encrypted, metadata
+	}
+	decrypt := func(encrypted []byte, metadata map[string]string) ([]byte, error) {
+		r, err := m.decrypt(context.Background(), bytes.NewReader(encrypted), metadata)
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(r)
+	}
+
+	for _, size := range []int{0, 1, cseChunkSize - 1, cseChunkSize, cseChunkSize + 1, 3 * cseChunkSize} {
+		data := make([]byte, size)
+		if _, err := rand.Read(data); err != nil {
+			t.Fatal(err)
+		}
+		encrypted, metadata := encrypt(t, data)
+		// Short plaintext may appear in the ciphertext by chance.
+		if size >= 16 && bytes.Contains(encrypted, data) {
+			t.Errorf("Size %d: encrypted data contains the plaintext", size)
+		}
+		decrypted, err := decrypt(encrypted, metadata)
+		if err != nil {
+			t.Fatalf("Size %d: %v", size, err)
+		}
+		if !bytes.Equal(data, decrypted) {
+			t.Errorf("Size %d: decrypted data differs", size)
+		}
+	}
+
+	data := bytes.Repeat([]byte("data"), cseChunkSize)
+	encrypted, metadata := encrypt(t, data)
+	chunk := cseChunkSize + 16
+
+	testCases := map[string][]byte{
+		"Modified":      append(append([]byte{}, encrypted[:10]...), append([]byte{encrypted[10] ^ 1}, encrypted[11:]...)...),
+		"Truncated":     encrypted[:2*chunk],
+		"ChunkRemoved":  append(append([]byte{}, encrypted[:chunk]...), encrypted[2*chunk:]...),
+		"ChunkSwapped":  append(append(append([]byte{}, encrypted[chunk:2*chunk]...), encrypted[:chunk]...), encrypted[2*chunk:]...),
+		"PartialChunk":  encrypted[:len(encrypted)-1],
+		"ExtraTrailing": append(append([]byte{}, encrypted...), 0),
+	}
+	for name, tampered := range testCases {
+		if _, err := decrypt(tampered, metadata); err == nil {
+			t.Errorf("%s: tampered data should not be decrypted", name)
+		}
+	}
+
+	plain, err := decrypt(data, nil)
+	if err != nil || !bytes.Equal(data, plain) {
+		t.Errorf("Object without encryption metadata should be returned as is: %v", err)
+	}
+}
@@ -14,10 +14,89 @@
 package s3sync
 
 import (
+	"fmt"
 	"strings"
 	"sync"
 )
 
+// SyncError is the error returned if some of the files are failed to be synced.
+// The errors can be inspected by errors.Is and errors.As.
+type SyncError struct {
+	// Errors is the list of the errors in order of occurrence.
+	// Each of them is *FileError, *ListError or another error
+	// not associated with any file.
+	Errors []error
+}
+
+func (e *SyncError) Error() string {
+	var errMsgs []string
+	for _, err := range e.Errors {
+		errMsgs = append(errMsgs, err.Error())
+	}
+	return strings.Join(errMsgs, "\n")
+}
+
+func (e *SyncError) Unwrap() []error {
+	return e.Errors
+}
+
+// FileErrors returns the errors of the file operations.
+func (e *SyncError) FileErrors() []*FileError {
+	var errs []*FileError
+	for _, err := range e.Errors {
+		if fe, ok := err.(*FileError); ok {
+			errs = append(errs, fe)
+		}
+	}
+	return errs
+}
+
+// ListErrors returns the errors occurred while listing the files.
+func (e *SyncError) ListErrors() []*ListError {
+	var errs []*ListError
+	for _, err := range e.Errors {
+		if le, ok := err.(*ListError); ok {
+			errs = append(errs, le)
+		}
+	}
+	return errs
+}
+
+// FileError is the error of the operation of a file.
+type FileError struct {
+	Op OperationType
+	// Source and Dest are the same as the ones of Operation.
+	Source string
+	Dest   string
+	Err    error
+}
+
+func (e *FileError) Error() string {
+	if e.Source == "" {
+		return fmt.Sprintf("%s %s: %v", e.Op, e.Dest, e.Err)
+	}
+	return fmt.Sprintf("%s %s to %s: %v", e.Op, e.Source, e.Dest, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// ListError is the error occurred while listing the files under the path.
+type ListError struct {
+	// Path is the S3 URL or the local path.
+	Path string
+	Err  error
+}
+
+func (e *ListError) Error() string {
+	return fmt.Sprintf("list %s: %v", e.Path, e.Err)
+}
+
+func (e *ListError) Unwrap() error {
+	return e.Err
+}
+
 type multiErr struct {
 	mu  sync.Mutex
 	err []error
@@ -42,6 +121,20 @@ func (e *multiErr) ErrOrNil() error {
 	return nil
 }
 
+// SyncErrOrNil returns SyncError of the errors or nil if there is no error.
+func (e *multiErr) SyncErrOrNil() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.err) > 0 {
+		return &SyncError{Errors: append([]error(nil), e.err...)}
+	}
+	return nil
+}
+
+func (e *multiErr) Unwrap() []error {
+	return e.err
+}
+
 func (e *multiErr) Error() string {
 	var errMsgs []string
 	for _, err := range e.err {
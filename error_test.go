@@ -40,3 +40,50 @@ func TestMultiErr(t *testing.T) {
 		}
 	})
 }
+
+func TestSyncError(t *testing.T) {
+	errNotFound := errors.New("not found")
+	errDenied := errors.New("access denied")
+	fileErr := &FileError{Op: OperationUpload, Source: "/tmp/foo", Dest: "s3://bucket/foo", Err: errNotFound}
+	deleteErr := &FileError{Op: OperationDelete, Dest: "s3://bucket/bar", Err: errDenied}
+	listErr := &ListError{Path: "s3://bucket/", Err: errDenied}
+
+	errs := &multiErr{}
+	if errs.SyncErrOrNil() != nil {
+		t.Fatal("Empty multiErr should return nil error")
+	}
+	errs.Append(fileErr)
+	errs.Append(listErr)
+	errs.Append(deleteErr)
+
+	err := errs.SyncErrOrNil()
+	expectedMsg := "upload /tmp/foo to s3://bucket/foo: not found\n" +
+		"list s3://bucket/: access denied\n" +
+		"delete s3://bucket/bar: access denied"
+	if err.Error() != expectedMsg {
+		t.Errorf("Unexpected error message: %s", err.Error())
+	}
+
+	var syncErr *SyncError
+	if !errors.As(err, &syncErr) {
+		t.Fatal("SyncError is expected")
+	}
+	if fe := syncErr.FileErrors(); len(fe) != 2 || fe[0] != fileErr || fe[1] != deleteErr {
+		t.Errorf("Unexpected file errors: %v", fe)
+	}
+	if le := syncErr.ListErrors(); len(le) != 1 || le[0] != listErr {
+		t.Errorf("Unexpected list errors: %v", le)
+	}
+
+	if !errors.Is(err, errNotFound) || !errors.Is(err, errDenied) {
+		t.Error("Wrapped errors should be found by errors.Is")
+	}
+	var fe *FileError
+	if !errors.As(err, &fe) || fe != fileErr {
+		t.Error("FileError should be found by errors.As")
+	}
+	var le *ListError
+	if !errors.As(err, &le) || le != listErr {
+		t.Error("ListError should be found by errors.As")
+	}
+}
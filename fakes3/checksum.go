@@ -0,0 +1,90 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakes3
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"hash/crc32"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// checksums holds the base64 encoded additional checksums of an object or a part.
+type checksums struct {
+	crc32  *string
+	crc32c *string
+	sha1   *string
+	sha256 *string
+
+	raw []byte
+}
+
+func newHash(alg types.ChecksumAlgorithm) hash.Hash {
+	switch alg {
+	case types.ChecksumAlgorithmCrc32:
+		return crc32.NewIEEE()
+	case types.ChecksumAlgorithmCrc32c:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case types.ChecksumAlgorithmSha1:
+		return sha1.New()
+	case types.ChecksumAlgorithmSha256:
+		return sha256.New()
+	}
+	return nil
+}
+
+func (c *checksums) set(alg types.ChecksumAlgorithm, s string) {
+	switch alg {
+	case types.ChecksumAlgorithmCrc32:
+		c.crc32 = &s
+	case types.ChecksumAlgorithmCrc32c:
+		c.crc32c = &s
+	case types.ChecksumAlgorithmSha1:
+		c.sha1 = &s
+	case types.ChecksumAlgorithmSha256:
+		c.sha256 = &s
+	}
+}
+
+func computeChecksums(alg types.ChecksumAlgorithm, data []byte) checksums {
+	var c checksums
+	h := newHash(alg)
+	if h == nil {
+		return c
+	}
+	h.Write(data)
+	c.raw = h.Sum(nil)
+	c.set(alg, base64.StdEncoding.EncodeToString(c.raw))
+	return c
+}
+
+// compositeChecksums calculates the checksum of the concatenated part checksums
+// suffixed by the number of the parts, as S3 does for COMPOSITE checksum type.
+func compositeChecksums(alg types.ChecksumAlgorithm, digests [][]byte) checksums {
+	var c checksums
+	h := newHash(alg)
+	if h == nil {
+		return c
+	}
+	for _, d := range digests {
+		h.Write(d)
+	}
+	c.raw = h.Sum(nil)
+	c.set(alg, fmt.Sprintf("%s-%d", base64.StdEncoding.EncodeToString(c.raw), len(digests)))
+	return c
+}
@@ -0,0 +1,987 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fakes3 provides an in-memory implementation of the subset of the
+// Amazon S3 API used by s3sync.
+//
+// The Client implements s3sync.S3API and can be passed to s3sync.NewWithClient
+// to test sync behavior hermetically, without network access or an S3
+// compatible server.
+package fakes3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	// DefaultMaxKeys is the default number of keys returned by a ListObjectsV2 call.
+	DefaultMaxKeys = 1000
+	// DefaultMinPartSize is the default minimum size of the non-last parts
+	// of a multipart upload.
+	DefaultMinPartSize = 5 * 1024 * 1024
+)
+
+// Client is an in-memory S3 client.
+// It is safe for concurrent use.
+type Client struct {
+	// Now returns the time recorded as LastModified of the stored objects.
+	// Times are truncated to seconds as S3 does.
+	Now func() time.Time
+	// MinPartSize is the minimum size of the parts of a multipart upload
+	// except the last one.
+	MinPartSize int64
+
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	uploadID int
+}
+
+type bucket struct {
+	objects map[string]*object
+	uploads map[string]*multipartUpload
+}
+
+type attributes struct {
+	contentType        *string
+	contentEncoding    *string
+	contentDisposition *string
+	contentLanguage    *string
+	cacheControl       *string
+	expires            *time.Time
+	metadata           map[string]string
+	storageClass       types.StorageClass
+	tagging            string
+
+	sse                  types.ServerSideEncryption
+	sseKMSKeyID          *string
+	sseKMSContext        *string
+	bucketKeyEnabled     *bool
+	sseCustomerAlgorithm *string
+	sseCustomerKeyMD5    *string
+
+	checksumAlgorithm types.ChecksumAlgorithm
+	checksumType      types.ChecksumType
+}
+
+type object struct {
+	data         []byte
+	etag         string
+	lastModified time.Time
+	attrs        attributes
+	checksums    checksums
+	partsCount   int32
+}
+
+type part struct {
+	data         []byte
+	etag         string
+	lastModified time.Time
+	checksums    checksums
+}
+
+type multipartUpload struct {
+	key   string
+	attrs attributes
+	parts map[int32]*part
+}
+
+// New returns an empty Client with the given buckets.
+func New(buckets ...string) *Client {
+	c := &Client{
+		Now:         time.Now,
+		MinPartSize: DefaultMinPartSize,
+		buckets:     make(map[string]*bucket),
+	}
+	for _, b := range buckets {
+		c.buckets[b] = newBucket()
+	}
+	return c
+}
+
+func newBucket() *bucket {
+	return &bucket{
+		objects: make(map[string]*object),
+		uploads: make(map[string]*multipartUpload),
+	}
+}
+
+func (c *Client) now() time.Time {
+	return c.Now().UTC().Truncate(time.Second)
+}
+
+func (c *Client) bucket(name *string) (*bucket, error) {
+	b, ok := c.buckets[aws.ToString(name)]
+	if !ok {
+		return nil, &types.NoSuchBucket{Message: aws.String("The specified bucket does not exist")}
+	}
+	return b, nil
+}
+
+func (c *Client) object(bucketName, key *string) (*object, error) {
+	b, err := c.bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+	o, ok := b.objects[aws.ToString(key)]
+	if !ok {
+		return nil, &types.NoSuchKey{Message: aws.String("The specified key does not exist.")}
+	}
+	return o, nil
+}
+
+func apiError(code, msg string) error {
+	return &smithy.GenericAPIError{Code: code, Message: msg}
+}
+
+func etagOf(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// CreateBucket creates a bucket.
+func (c *Client) CreateBucket(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	name := aws.ToString(params.Bucket)
+	if _, ok := c.buckets[name]; ok {
+		return nil, &types.BucketAlreadyOwnedByYou{Message: aws.String("Bucket already exists")}
+	}
+	c.buckets[name] = newBucket()
+	return &s3.CreateBucketOutput{Location: aws.String("/" + name)}, nil
+}
+
+// PutObject stores an object.
+func (c *Client) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	var data []byte
+	if params.Body != nil {
+		var err error
+		if data, err = io.ReadAll(params.Body); err != nil {
+			return nil, err
+		}
+	}
+	attrs := attributes{
+		contentType:          params.ContentType,
+		contentEncoding:      params.ContentEncoding,
+		contentDisposition:   params.ContentDisposition,
+		contentLanguage:      params.ContentLanguage,
+		cacheControl:         params.CacheControl,
+		expires:              params.Expires,
+		metadata:             copyMetadata(params.Metadata),
+		storageClass:         params.StorageClass,
+		tagging:              aws.ToString(params.Tagging),
+		sse:                  params.ServerSideEncryption,
+		sseKMSKeyID:          params.SSEKMSKeyId,
+		sseKMSContext:        params.SSEKMSEncryptionContext,
+		bucketKeyEnabled:     params.BucketKeyEnabled,
+		sseCustomerAlgorithm: params.SSECustomerAlgorithm,
+		checksumAlgorithm:    params.ChecksumAlgorithm,
+		checksumType:         types.ChecksumTypeFullObject,
+	}
+	keyMD5, err := customerKeyMD5(params.SSECustomerKey, params.SSECustomerKeyMD5)
+	if err != nil {
+		return nil, err
+	}
+	attrs.sseCustomerKeyMD5 = keyMD5
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, err := c.bucket(params.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	o := &object{
+		data:         data,
+		etag:         etagOf(data),
+		lastModified: c.now(),
+		attrs:        attrs,
+		checksums:    computeChecksums(params.ChecksumAlgorithm, data),
+	}
+	b.objects[aws.ToString(params.Key)] = o
+	return &s3.PutObjectOutput{
+		ETag:                 aws.String(o.etag),
+		ServerSideEncryption: attrs.sse,
+		SSEKMSKeyId:          attrs.sseKMSKeyID,
+		SSECustomerAlgorithm: attrs.sseCustomerAlgorithm,
+		SSECustomerKeyMD5:    attrs.sseCustomerKeyMD5,
+		BucketKeyEnabled:     attrs.bucketKeyEnabled,
+		ChecksumCRC32:        o.checksums.crc32,
+		ChecksumCRC32C:       o.checksums.crc32c,
+		ChecksumSHA1:         o.checksums.sha1,
+		ChecksumSHA256:       o.checksums.sha256,
+		ChecksumType:         o.checksumType(),
+		Size:                 aws.Int64(int64(len(data))),
+	}, nil
+}
+
+// GetObject returns the stored object.
+// Range, IfMatch and SSE-C parameters are supported.
+func (c *Client) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	o, err := c.object(params.Bucket, params.Key)
+	if err != nil {
+		return nil, err
+	}
+	if params.IfMatch != nil && *params.IfMatch != o.etag {
+		return nil, apiError("PreconditionFailed", "At least one of the pre-conditions you specified did not hold")
+	}
+	if err := o.checkCustomerKey(params.SSECustomerKey, params.SSECustomerKeyMD5); err != nil {
+		return nil, err
+	}
+
+	data := o.data
+	var contentRange *string
+	if params.Range != nil && len(o.data) > 0 {
+		start, end, err := parseRange(*params.Range, int64(len(o.data)))
+		if err != nil {
+			return nil, err
+		}
+		data = o.data[start : end+1]
+		contentRange = aws.String(fmt.Sprintf("bytes %d-%d/%d", start, end, len(o.data)))
+	}
+
+	out := &s3.GetObjectOutput{
+		Body:                 io.NopCloser(bytes.NewReader(data)),
+		ContentLength:        aws.Int64(int64(len(data))),
+		ContentRange:         contentRange,
+		AcceptRanges:         aws.String("bytes"),
+		ETag:                 aws.String(o.etag),
+		LastModified:         aws.Time(o.lastModified),
+		ContentType:          o.attrs.contentType,
+		ContentEncoding:      o.attrs.contentEncoding,
+		ContentDisposition:   o.attrs.contentDisposition,
+		ContentLanguage:      o.attrs.contentLanguage,
+		CacheControl:         o.attrs.cacheControl,
+		Expires:              o.attrs.expires,
+		Metadata:             copyMetadata(o.attrs.metadata),
+		StorageClass:         o.attrs.storageClass,
+		ServerSideEncryption: o.attrs.sse,
+		SSEKMSKeyId:          o.attrs.sseKMSKeyID,
+		BucketKeyEnabled:     o.attrs.bucketKeyEnabled,
+		SSECustomerAlgorithm: o.attrs.sseCustomerAlgorithm,
+		SSECustomerKeyMD5:    o.attrs.sseCustomerKeyMD5,
+		TagCount:             o.tagCount(),
+	}
+	if o.partsCount > 0 {
+		out.PartsCount = aws.Int32(o.partsCount)
+	}
+	if params.ChecksumMode == types.ChecksumModeEnabled && contentRange == nil {
+		out.ChecksumCRC32 = o.checksums.crc32
+		out.ChecksumCRC32C = o.checksums.crc32c
+		out.ChecksumSHA1 = o.checksums.sha1
+		out.ChecksumSHA256 = o.checksums.sha256
+		out.ChecksumType = o.checksumType()
+	}
+	return out, nil
+}
+
+// HeadObject returns the attributes of the stored object.
+func (c *Client) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	o, err := c.object(params.Bucket, params.Key)
+	if err != nil {
+		var nsk *types.NoSuchKey
+		if errors.As(err, &nsk) {
+			return nil, &types.NotFound{Message: aws.String("Not Found")}
+		}
+		return nil, err
+	}
+	if params.IfMatch != nil && *params.IfMatch != o.etag {
+		return nil, apiError("PreconditionFailed", "At least one of the pre-conditions you specified did not hold")
+	}
+	if err := o.checkCustomerKey(params.SSECustomerKey, params.SSECustomerKeyMD5); err != nil {
+		return nil, err
+	}
+	out := &s3.HeadObjectOutput{
+		ContentLength:        aws.Int64(int64(len(o.data))),
+		AcceptRanges:         aws.String("bytes"),
+		ETag:                 aws.String(o.etag),
+		LastModified:         aws.Time(o.lastModified),
+		ContentType:          o.attrs.contentType,
+		ContentEncoding:      o.attrs.contentEncoding,
+		ContentDisposition:   o.attrs.contentDisposition,
+		ContentLanguage:      o.attrs.contentLanguage,
+		CacheControl:         o.attrs.cacheControl,
+		Expires:              o.attrs.expires,
+		Metadata:             copyMetadata(o.attrs.metadata),
+		StorageClass:         o.attrs.storageClass,
+		ServerSideEncryption: o.attrs.sse,
+		SSEKMSKeyId:          o.attrs.sseKMSKeyID,
+		BucketKeyEnabled:     o.attrs.bucketKeyEnabled,
+		SSECustomerAlgorithm: o.attrs.sseCustomerAlgorithm,
+		SSECustomerKeyMD5:    o.attrs.sseCustomerKeyMD5,
+		TagCount:             o.tagCount(),
+	}
+	if o.partsCount > 0 {
+		out.PartsCount = aws.Int32(o.partsCount)
+	}
+	if params.ChecksumMode == types.ChecksumModeEnabled {
+		out.ChecksumCRC32 = o.checksums.crc32
+		out.ChecksumCRC32C = o.checksums.crc32c
+		out.ChecksumSHA1 = o.checksums.sha1
+		out.ChecksumSHA256 = o.checksums.sha256
+		out.ChecksumType = o.checksumType()
+	}
+	return out, nil
+}
+
+// GetObjectTagging returns the tags of the stored object.
+func (c *Client) GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	o, err := c.object(params.Bucket, params.Key)
+	if err != nil {
+		return nil, err
+	}
+	tags := o.tags()
+	if tags == nil {
+		tags = []types.Tag{}
+	}
+	return &s3.GetObjectTaggingOutput{TagSet: tags}, nil
+}
+
+// DeleteObject deletes the object.
+// Deleting a non-existent key succeeds as S3 does.
+func (c *Client) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, err := c.bucket(params.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	delete(b.objects, aws.ToString(params.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+// CopyObject copies an object.
+// MetadataDirective and TaggingDirective are respected.
+func (c *Client) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	src, err := c.copySource(params.CopySource, params.CopySourceSSECustomerKey, params.CopySourceSSECustomerKeyMD5)
+	if err != nil {
+		return nil, err
+	}
+	if params.CopySourceIfMatch != nil && *params.CopySourceIfMatch != src.etag {
+		return nil, apiError("PreconditionFailed", "At least one of the pre-conditions you specified did not hold")
+	}
+	b, err := c.bucket(params.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	keyMD5, err := customerKeyMD5(params.SSECustomerKey, params.SSECustomerKeyMD5)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := src.attrs
+	if params.MetadataDirective == types.MetadataDirectiveReplace {
+		attrs.contentType = params.ContentType
+		attrs.contentEncoding = params.ContentEncoding
+		attrs.contentDisposition = params.ContentDisposition
+		attrs.contentLanguage = params.ContentLanguage
+		attrs.cacheControl = params.CacheControl
+		attrs.expires = params.Expires
+		attrs.metadata = copyMetadata(params.Metadata)
+	} else {
+		attrs.metadata = copyMetadata(src.attrs.metadata)
+	}
+	if params.TaggingDirective == types.TaggingDirectiveReplace {
+		attrs.tagging = aws.ToString(params.Tagging)
+	}
+	attrs.storageClass = params.StorageClass
+	attrs.sse = params.ServerSideEncryption
+	attrs.sseKMSKeyID = params.SSEKMSKeyId
+	attrs.sseKMSContext = params.SSEKMSEncryptionContext
+	attrs.bucketKeyEnabled = params.BucketKeyEnabled
+	attrs.sseCustomerAlgorithm = params.SSECustomerAlgorithm
+	attrs.sseCustomerKeyMD5 = keyMD5
+
+	o := &object{
+		data:         src.data,
+		etag:         src.etag,
+		lastModified: c.now(),
+		attrs:        attrs,
+		checksums:    src.checksums,
+		partsCount:   src.partsCount,
+	}
+	if params.ChecksumAlgorithm != "" {
+		o.attrs.checksumAlgorithm = params.ChecksumAlgorithm
+		o.attrs.checksumType = types.ChecksumTypeFullObject
+		o.checksums = computeChecksums(params.ChecksumAlgorithm, o.data)
+	}
+	if src.partsCount > 0 {
+		// S3 computes the ETag of the copied object as a single part object.
+		o.etag = etagOf(o.data)
+		o.partsCount = 0
+	}
+	b.objects[aws.ToString(params.Key)] = o
+	return &s3.CopyObjectOutput{
+		CopyObjectResult: &types.CopyObjectResult{
+			ETag:         aws.String(o.etag),
+			LastModified: aws.Time(o.lastModified),
+		},
+		ServerSideEncryption: attrs.sse,
+		SSEKMSKeyId:          attrs.sseKMSKeyID,
+		SSECustomerAlgorithm: attrs.sseCustomerAlgorithm,
+		SSECustomerKeyMD5:    attrs.sseCustomerKeyMD5,
+	}, nil
+}
+
+func (c *Client) copySource(copySource, key, keyMD5 *string) (*object, error) {
+	src := aws.ToString(copySource)
+	if i := strings.Index(src, "?"); i >= 0 {
+		src = src[:i]
+	}
+	if s, err := url.PathUnescape(src); err == nil {
+		src = s
+	}
+	src = strings.TrimPrefix(src, "/")
+	bucketName, key2, ok := strings.Cut(src, "/")
+	if !ok {
+		return nil, apiError("InvalidArgument", "Invalid copy source object key")
+	}
+	o, err := c.object(&bucketName, &key2)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.checkCustomerKey(key, keyMD5); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// ListObjectsV2 lists the objects in lexicographical order.
+// Prefix, Delimiter, StartAfter, MaxKeys and ContinuationToken are supported.
+func (c *Client) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, err := c.bucket(params.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	prefix := aws.ToString(params.Prefix)
+	delimiter := aws.ToString(params.Delimiter)
+	maxKeys := int(aws.ToInt32(params.MaxKeys))
+	if params.MaxKeys == nil {
+		maxKeys = DefaultMaxKeys
+	}
+	after := aws.ToString(params.StartAfter)
+	if params.ContinuationToken != nil {
+		after = *params.ContinuationToken
+	}
+
+	keys := make([]string, 0, len(b.objects))
+	for k := range b.objects {
+		if strings.HasPrefix(k, prefix) && k > after {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	out := &s3.ListObjectsV2Output{
+		Name:              params.Bucket,
+		Prefix:            params.Prefix,
+		Delimiter:         params.Delimiter,
+		StartAfter:        params.StartAfter,
+		ContinuationToken: params.ContinuationToken,
+		MaxKeys:           aws.Int32(int32(maxKeys)),
+		IsTruncated:       aws.Bool(false),
+	}
+	var count int
+	var last string
+	for i := 0; i < len(keys); i++ {
+		k := keys[i]
+		if count >= maxKeys {
+			out.IsTruncated = aws.Bool(true)
+			out.NextContinuationToken = aws.String(last)
+			break
+		}
+		if delimiter != "" {
+			if j := strings.Index(k[len(prefix):], delimiter); j >= 0 {
+				cp := k[:len(prefix)+j+len(delimiter)]
+				out.CommonPrefixes = append(out.CommonPrefixes, types.CommonPrefix{Prefix: aws.String(cp)})
+				// Skip all keys having the same common prefix.
+				for i+1 < len(keys) && strings.HasPrefix(keys[i+1], cp) {
+					i++
+				}
+				last = keys[i]
+				count++
+				continue
+			}
+		}
+		o := b.objects[k]
+		obj := types.Object{
+			Key:          aws.String(k),
+			ETag:         aws.String(o.etag),
+			LastModified: aws.Time(o.lastModified),
+			Size:         aws.Int64(int64(len(o.data))),
+			StorageClass: types.ObjectStorageClass(o.storageClass()),
+		}
+		if o.attrs.checksumAlgorithm != "" {
+			obj.ChecksumAlgorithm = []types.ChecksumAlgorithm{o.attrs.checksumAlgorithm}
+			obj.ChecksumType = o.checksumType()
+		}
+		out.Contents = append(out.Contents, obj)
+		last = k
+		count++
+	}
+	out.KeyCount = aws.Int32(int32(count))
+	return out, nil
+}
+
+// CreateMultipartUpload initiates a multipart upload.
+func (c *Client) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	keyMD5, err := customerKeyMD5(params.SSECustomerKey, params.SSECustomerKeyMD5)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, err := c.bucket(params.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	checksumType := params.ChecksumType
+	if params.ChecksumAlgorithm != "" && checksumType == "" {
+		checksumType = types.ChecksumTypeComposite
+	}
+	c.uploadID++
+	id := strconv.Itoa(c.uploadID)
+	b.uploads[id] = &multipartUpload{
+		key: aws.ToString(params.Key),
+		attrs: attributes{
+			contentType:          params.ContentType,
+			contentEncoding:      params.ContentEncoding,
+			contentDisposition:   params.ContentDisposition,
+			contentLanguage:      params.ContentLanguage,
+			cacheControl:         params.CacheControl,
+			expires:              params.Expires,
+			metadata:             copyMetadata(params.Metadata),
+			storageClass:         params.StorageClass,
+			tagging:              aws.ToString(params.Tagging),
+			sse:                  params.ServerSideEncryption,
+			sseKMSKeyID:          params.SSEKMSKeyId,
+			sseKMSContext:        params.SSEKMSEncryptionContext,
+			bucketKeyEnabled:     params.BucketKeyEnabled,
+			sseCustomerAlgorithm: params.SSECustomerAlgorithm,
+			sseCustomerKeyMD5:    keyMD5,
+			checksumAlgorithm:    params.ChecksumAlgorithm,
+			checksumType:         checksumType,
+		},
+		parts: make(map[int32]*part),
+	}
+	return &s3.CreateMultipartUploadOutput{
+		Bucket:            params.Bucket,
+		Key:               params.Key,
+		UploadId:          aws.String(id),
+		ChecksumAlgorithm: params.ChecksumAlgorithm,
+		ChecksumType:      checksumType,
+	}, nil
+}
+
+func (c *Client) upload(bucketName, key, uploadID *string) (*multipartUpload, error) {
+	b, err := c.bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+	u, ok := b.uploads[aws.ToString(uploadID)]
+	if !ok || u.key != aws.ToString(key) {
+		return nil, &types.NoSuchUpload{Message: aws.String("The specified upload does not exist.")}
+	}
+	return u, nil
+}
+
+func (u *multipartUpload) checkCustomerKey(key, keyMD5 *string) error {
+	got, err := customerKeyMD5(key, keyMD5)
+	if err != nil {
+		return err
+	}
+	if aws.ToString(got) != aws.ToString(u.attrs.sseCustomerKeyMD5) {
+		return apiError("InvalidRequest", "The provided encryption parameters did not match the ones used originally.")
+	}
+	return nil
+}
+
+// UploadPart uploads a part of the multipart upload.
+func (c *Client) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	var data []byte
+	if params.Body != nil {
+		var err error
+		if data, err = io.ReadAll(params.Body); err != nil {
+			return nil, err
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	u, err := c.upload(params.Bucket, params.Key, params.UploadId)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.checkCustomerKey(params.SSECustomerKey, params.SSECustomerKeyMD5); err != nil {
+		return nil, err
+	}
+	p := &part{
+		data:         data,
+		etag:         etagOf(data),
+		lastModified: c.now(),
+		checksums:    computeChecksums(u.attrs.checksumAlgorithm, data),
+	}
+	u.parts[aws.ToInt32(params.PartNumber)] = p
+	return &s3.UploadPartOutput{
+		ETag:           aws.String(p.etag),
+		ChecksumCRC32:  p.checksums.crc32,
+		ChecksumCRC32C: p.checksums.crc32c,
+		ChecksumSHA1:   p.checksums.sha1,
+		ChecksumSHA256: p.checksums.sha256,
+	}, nil
+}
+
+// UploadPartCopy uploads a part of the multipart upload by copying
+// the range of the existing object.
+func (c *Client) UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	src, err := c.copySource(params.CopySource, params.CopySourceSSECustomerKey, params.CopySourceSSECustomerKeyMD5)
+	if err != nil {
+		return nil, err
+	}
+	if params.CopySourceIfMatch != nil && *params.CopySourceIfMatch != src.etag {
+		return nil, apiError("PreconditionFailed", "At least one of the pre-conditions you specified did not hold")
+	}
+	u, err := c.upload(params.Bucket, params.Key, params.UploadId)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.checkCustomerKey(params.SSECustomerKey, params.SSECustomerKeyMD5); err != nil {
+		return nil, err
+	}
+	data := src.data
+	if params.CopySourceRange != nil {
+		start, end, err := parseRange(*params.CopySourceRange, int64(len(src.data)))
+		if err != nil {
+			return nil, err
+		}
+		data = src.data[start : end+1]
+	}
+	p := &part{
+		data:         data,
+		etag:         etagOf(data),
+		lastModified: c.now(),
+		checksums:    computeChecksums(u.attrs.checksumAlgorithm, data),
+	}
+	u.parts[aws.ToInt32(params.PartNumber)] = p
+	return &s3.UploadPartCopyOutput{
+		CopyPartResult: &types.CopyPartResult{
+			ETag:           aws.String(p.etag),
+			LastModified:   aws.Time(p.lastModified),
+			ChecksumCRC32:  p.checksums.crc32,
+			ChecksumCRC32C: p.checksums.crc32c,
+			ChecksumSHA1:   p.checksums.sha1,
+			ChecksumSHA256: p.checksums.sha256,
+		},
+	}, nil
+}
+
+// ListParts lists the uploaded parts of the multipart upload.
+func (c *Client) ListParts(ctx context.Context, params *s3.ListPartsInput, optFns ...func(*s3.Options)) (*s3.ListPartsOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	u, err := c.upload(params.Bucket, params.Key, params.UploadId)
+	if err != nil {
+		return nil, err
+	}
+	marker := 0
+	if params.PartNumberMarker != nil {
+		if marker, err = strconv.Atoi(*params.PartNumberMarker); err != nil {
+			return nil, apiError("InvalidArgument", "Invalid part number marker")
+		}
+	}
+	maxParts := int(aws.ToInt32(params.MaxParts))
+	if maxParts <= 0 {
+		maxParts = 1000
+	}
+
+	nums := make([]int, 0, len(u.parts))
+	for n := range u.parts {
+		if int(n) > marker {
+			nums = append(nums, int(n))
+		}
+	}
+	sort.Ints(nums)
+
+	out := &s3.ListPartsOutput{
+		Bucket:            params.Bucket,
+		Key:               params.Key,
+		UploadId:          params.UploadId,
+		MaxParts:          aws.Int32(int32(maxParts)),
+		PartNumberMarker:  params.PartNumberMarker,
+		IsTruncated:       aws.Bool(false),
+		ChecksumAlgorithm: u.attrs.checksumAlgorithm,
+		ChecksumType:      u.attrs.checksumType,
+		StorageClass:      u.attrs.storageClass,
+	}
+	for i, n := range nums {
+		if i >= maxParts {
+			out.IsTruncated = aws.Bool(true)
+			out.NextPartNumberMarker = aws.String(strconv.Itoa(nums[i-1]))
+			break
+		}
+		p := u.parts[int32(n)]
+		out.Parts = append(out.Parts, types.Part{
+			PartNumber:     aws.Int32(int32(n)),
+			ETag:           aws.String(p.etag),
+			LastModified:   aws.Time(p.lastModified),
+			Size:           aws.Int64(int64(len(p.data))),
+			ChecksumCRC32:  p.checksums.crc32,
+			ChecksumCRC32C: p.checksums.crc32c,
+			ChecksumSHA1:   p.checksums.sha1,
+			ChecksumSHA256: p.checksums.sha256,
+		})
+	}
+	return out, nil
+}
+
+// CompleteMultipartUpload assembles the uploaded parts into an object.
+func (c *Client) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, err := c.bucket(params.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	u, err := c.upload(params.Bucket, params.Key, params.UploadId)
+	if err != nil {
+		return nil, err
+	}
+	if params.MultipartUpload == nil || len(params.MultipartUpload.Parts) == 0 {
+		return nil, apiError("MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema.")
+	}
+
+	var (
+		data     []byte
+		md5s     []byte
+		digests  [][]byte
+		prevPart int32
+	)
+	completed := params.MultipartUpload.Parts
+	for i, cp := range completed {
+		n := aws.ToInt32(cp.PartNumber)
+		if n <= prevPart {
+			return nil, apiError("InvalidPartOrder", "The list of parts was not in ascending order.")
+		}
+		prevPart = n
+		p, ok := u.parts[n]
+		if !ok || aws.ToString(cp.ETag) != p.etag {
+			return nil, apiError("InvalidPart", "One or more of the specified parts could not be found.")
+		}
+		if i < len(completed)-1 && int64(len(p.data)) < c.MinPartSize {
+			return nil, apiError("EntityTooSmall", "Your proposed upload is smaller than the minimum allowed object size.")
+		}
+		data = append(data, p.data...)
+		sum := md5.Sum(p.data)
+		md5s = append(md5s, sum[:]...)
+		digests = append(digests, p.checksums.raw)
+	}
+	sum := md5.Sum(md5s)
+
+	o := &object{
+		data:         data,
+		etag:         fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(sum[:]), len(completed)),
+		lastModified: c.now(),
+		attrs:        u.attrs,
+		partsCount:   int32(len(completed)),
+	}
+	if alg := u.attrs.checksumAlgorithm; alg != "" {
+		if u.attrs.checksumType == types.ChecksumTypeFullObject {
+			o.checksums = computeChecksums(alg, data)
+		} else {
+			o.checksums = compositeChecksums(alg, digests)
+		}
+	}
+	delete(b.uploads, aws.ToString(params.UploadId))
+	b.objects[u.key] = o
+	return &s3.CompleteMultipartUploadOutput{
+		Bucket:               params.Bucket,
+		Key:                  params.Key,
+		ETag:                 aws.String(o.etag),
+		ServerSideEncryption: o.attrs.sse,
+		SSEKMSKeyId:          o.attrs.sseKMSKeyID,
+		BucketKeyEnabled:     o.attrs.bucketKeyEnabled,
+		ChecksumCRC32:        o.checksums.crc32,
+		ChecksumCRC32C:       o.checksums.crc32c,
+		ChecksumSHA1:         o.checksums.sha1,
+		ChecksumSHA256:       o.checksums.sha256,
+		ChecksumType:         o.checksumType(),
+	}, nil
+}
+
+// AbortMultipartUpload aborts the multipart upload and discards the uploaded parts.
+func (c *Client) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, err := c.bucket(params.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.upload(params.Bucket, params.Key, params.UploadId); err != nil {
+		return nil, err
+	}
+	delete(b.uploads, aws.ToString(params.UploadId))
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func (o *object) storageClass() types.StorageClass {
+	if o.attrs.storageClass == "" {
+		return types.StorageClassStandard
+	}
+	return o.attrs.storageClass
+}
+
+func (o *object) checksumType() types.ChecksumType {
+	if o.attrs.checksumAlgorithm == "" {
+		return ""
+	}
+	return o.attrs.checksumType
+}
+
+func (o *object) tags() []types.Tag {
+	if o.attrs.tagging == "" {
+		return nil
+	}
+	q, err := url.ParseQuery(o.attrs.tagging)
+	if err != nil {
+		return nil
+	}
+	tags := make([]types.Tag, 0, len(q))
+	for k, v := range q {
+		tags = append(tags, types.Tag{Key: aws.String(k), Value: aws.String(v[0])})
+	}
+	sort.Slice(tags, func(i, j int) bool { return *tags[i].Key < *tags[j].Key })
+	return tags
+}
+
+func (o *object) tagCount() *int32 {
+	if n := len(o.tags()); n > 0 {
+		return aws.Int32(int32(n))
+	}
+	return nil
+}
+
+func (o *object) checkCustomerKey(key, keyMD5 *string) error {
+	got, err := customerKeyMD5(key, keyMD5)
+	if err != nil {
+		return err
+	}
+	want := aws.ToString(o.attrs.sseCustomerKeyMD5)
+	switch {
+	case want == "" && got == nil:
+		return nil
+	case want == "":
+		return apiError("InvalidRequest", "The encryption parameters are not applicable to this object.")
+	case aws.ToString(got) != want:
+		return apiError("InvalidRequest", "The object was stored using a form of Server Side Encryption. The correct parameters must be provided to retrieve the object.")
+	}
+	return nil
+}
+
+// customerKeyMD5 validates the base64 encoded SSE-C key and returns its base64 encoded MD5 digest.
+func customerKeyMD5(key, keyMD5 *string) (*string, error) {
+	if key == nil {
+		return nil, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(*key)
+	if err != nil || len(raw) != 32 {
+		return nil, apiError("InvalidArgument", "The secret key was invalid for the specified algorithm.")
+	}
+	sum := md5.Sum(raw)
+	s := base64.StdEncoding.EncodeToString(sum[:])
+	if keyMD5 != nil && *keyMD5 != s {
+		return nil, apiError("InvalidArgument", "The calculated MD5 hash of the key did not match the hash that was provided.")
+	}
+	return &s, nil
+}
+
+func copyMetadata(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	ret := make(map[string]string, len(m))
+	for k, v := range m {
+		ret[strings.ToLower(k)] = v
+	}
+	return ret
+}
+
+// parseRange parses HTTP range header value and returns the inclusive byte range.
+func parseRange(r string, size int64) (int64, int64, error) {
+	invalid := apiError("InvalidRange", "The requested range is not satisfiable")
+	spec, ok := strings.CutPrefix(r, "bytes=")
+	if !ok {
+		return 0, 0, invalid
+	}
+	s, e, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, 0, invalid
+	}
+	var start, end int64
+	var err error
+	switch {
+	case s == "":
+		// Suffix range
+		n, err := strconv.ParseInt(e, 10, 64)
+		if err != nil {
+			return 0, 0, invalid
+		}
+		start, end = size-n, size-1
+		if start < 0 {
+			start = 0
+		}
+	default:
+		if start, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return 0, 0, invalid
+		}
+		end = size - 1
+		if e != "" {
+			if end, err = strconv.ParseInt(e, 10, 64); err != nil {
+				return 0, 0, invalid
+			}
+		}
+	}
+	if end >= size {
+		end = size - 1
+	}
+	if start >= size || start > end {
+		return 0, 0, invalid
+	}
+	return start, end, nil
+}
@@ -0,0 +1,359 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package fakes3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+func put(t *testing.T, c *Client, key string, data []byte) {
+	t.Helper()
+	_, err := c.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func get(t *testing.T, c *Client, key string, rng *string) []byte {
+	t.Helper()
+	out, err := c.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String(key),
+		Range:  rng,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Body.Close()
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func errorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return ""
+}
+
+func TestListObjectsV2(t *testing.T) {
+	c := New("bucket")
+	for _, key := range []string{"a", "dir/b", "dir/c", "dir/sub/d", "e"} {
+		put(t, c, key, []byte(key))
+	}
+
+	testCases := map[string]struct {
+		input            s3.ListObjectsV2Input
+		expectedKeys     [][]string
+		expectedPrefixes [][]string
+	}{
+		"All": {
+			input:            s3.ListObjectsV2Input{},
+			expectedKeys:     [][]string{{"a", "dir/b", "dir/c", "dir/sub/d", "e"}},
+			expectedPrefixes: [][]string{nil},
+		},
+		"Prefix": {
+			input:            s3.ListObjectsV2Input{Prefix: aws.String("dir/")},
+			expectedKeys:     [][]string{{"dir/b", "dir/c", "dir/sub/d"}},
+			expectedPrefixes: [][]string{nil},
+		},
+		"Delimiter": {
+			input:            s3.ListObjectsV2Input{Prefix: aws.String("dir/"), Delimiter: aws.String("/")},
+			expectedKeys:     [][]string{{"dir/b", "dir/c"}},
+			expectedPrefixes: [][]string{{"dir/sub/"}},
+		},
+		"Pagination": {
+			input:            s3.ListObjectsV2Input{MaxKeys: aws.Int32(2)},
+			expectedKeys:     [][]string{{"a", "dir/b"}, {"dir/c", "dir/sub/d"}, {"e"}},
+			expectedPrefixes: [][]string{nil, nil, nil},
+		},
+		"PaginationWithDelimiter": {
+			input:            s3.ListObjectsV2Input{Delimiter: aws.String("/"), MaxKeys: aws.Int32(1)},
+			expectedKeys:     [][]string{{"a"}, nil, {"e"}},
+			expectedPrefixes: [][]string{nil, {"dir/"}, nil},
+		},
+		"StartAfter": {
+			input:            s3.ListObjectsV2Input{StartAfter: aws.String("dir/c")},
+			expectedKeys:     [][]string{{"dir/sub/d", "e"}},
+			expectedPrefixes: [][]string{nil},
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			input := tt.input
+			input.Bucket = aws.String("bucket")
+			var keys, prefixes [][]string
+			for {
+				out, err := c.ListObjectsV2(context.Background(), &input)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var k, p []string
+				for _, o := range out.Contents {
+					k = append(k, *o.Key)
+				}
+				for _, cp := range out.CommonPrefixes {
+					p = append(p, *cp.Prefix)
+				}
+				keys, prefixes = append(keys, k), append(prefixes, p)
+				if !aws.ToBool(out.IsTruncated) {
+					break
+				}
+				input.ContinuationToken = out.NextContinuationToken
+			}
+			if !reflect.DeepEqual(tt.expectedKeys, keys) {
+				t.Errorf("Expected keys %v, got %v", tt.expectedKeys, keys)
+			}
+			if !reflect.DeepEqual(tt.expectedPrefixes, prefixes) {
+				t.Errorf("Expected common prefixes %v, got %v", tt.expectedPrefixes, prefixes)
+			}
+		})
+	}
+
+	_, err := c.ListObjectsV2(context.Background(), &s3.ListObjectsV2Input{Bucket: aws.String("unknown")})
+	var noSuchBucket *types.NoSuchBucket
+	if !errors.As(err, &noSuchBucket) {
+		t.Errorf("NoSuchBucket is expected, got %v", err)
+	}
+}
+
+func TestGetObject(t *testing.T) {
+	c := New("bucket")
+	put(t, c, "key", []byte("0123456789"))
+
+	t.Run("Range", func(t *testing.T) {
+		testCases := map[string]string{
+			"bytes=2-4":  "234",
+			"bytes=7-":   "789",
+			"bytes=-2":   "89",
+			"bytes=8-20": "89",
+		}
+		for rng, expected := range testCases {
+			if data := get(t, c, "key", aws.String(rng)); string(data) != expected {
+				t.Errorf("%s: expected %q, got %q", rng, expected, string(data))
+			}
+		}
+		_, err := c.GetObject(context.Background(), &s3.GetObjectInput{
+			Bucket: aws.String("bucket"),
+			Key:    aws.String("key"),
+			Range:  aws.String("bytes=10-"),
+		})
+		if code := errorCode(err); code != "InvalidRange" {
+			t.Errorf("InvalidRange is expected, got %v", err)
+		}
+	})
+	t.Run("IfMatch", func(t *testing.T) {
+		head, err := c.HeadObject(context.Background(), &s3.HeadObjectInput{
+			Bucket: aws.String("bucket"),
+			Key:    aws.String("key"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.GetObject(context.Background(), &s3.GetObjectInput{
+			Bucket:  aws.String("bucket"),
+			Key:     aws.String("key"),
+			IfMatch: head.ETag,
+		}); err != nil {
+			t.Errorf("GetObject with matching ETag should succeed: %v", err)
+		}
+		_, err = c.GetObject(context.Background(), &s3.GetObjectInput{
+			Bucket:  aws.String("bucket"),
+			Key:     aws.String("key"),
+			IfMatch: aws.String(`"unknown"`),
+		})
+		if code := errorCode(err); code != "PreconditionFailed" {
+			t.Errorf("PreconditionFailed is expected, got %v", err)
+		}
+	})
+	t.Run("NoSuchKey", func(t *testing.T) {
+		_, err := c.GetObject(context.Background(), &s3.GetObjectInput{
+			Bucket: aws.String("bucket"),
+			Key:    aws.String("unknown"),
+		})
+		var noSuchKey *types.NoSuchKey
+		if !errors.As(err, &noSuchKey) {
+			t.Errorf("NoSuchKey is expected, got %v", err)
+		}
+	})
+}
+
+func TestMultipartUpload(t *testing.T) {
+	c := New("bucket")
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1024*1024) // 16MiB
+
+	_, err := manager.NewUploader(c, func(u *manager.Uploader) {
+		u.PartSize = DefaultMinPartSize
+	}).Upload(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := c.HeadObject(context.Background(), &s3.HeadObjectInput{
+		Bucket:     aws.String("bucket"),
+		Key:        aws.String("key"),
+		PartNumber: aws.Int32(1),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := aws.ToInt32(head.PartsCount); n != 4 {
+		t.Errorf("Expected 4 parts, got %d", n)
+	}
+	if !bytes.Equal(data, get(t, c, "key", nil)) {
+		t.Error("Uploaded data differs")
+	}
+
+	t.Run("EntityTooSmall", func(t *testing.T) {
+		create, err := c.CreateMultipartUpload(context.Background(), &s3.CreateMultipartUploadInput{
+			Bucket: aws.String("bucket"),
+			Key:    aws.String("small"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var parts []types.CompletedPart
+		for i := int32(1); i <= 2; i++ {
+			out, err := c.UploadPart(context.Background(), &s3.UploadPartInput{
+				Bucket:     aws.String("bucket"),
+				Key:        aws.String("small"),
+				UploadId:   create.UploadId,
+				PartNumber: aws.Int32(i),
+				Body:       bytes.NewReader([]byte(fmt.Sprint(i))),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			parts = append(parts, types.CompletedPart{ETag: out.ETag, PartNumber: aws.Int32(i)})
+		}
+		_, err = c.CompleteMultipartUpload(context.Background(), &s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String("bucket"),
+			Key:             aws.String("small"),
+			UploadId:        create.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+		})
+		if code := errorCode(err); code != "EntityTooSmall" {
+			t.Errorf("EntityTooSmall is expected, got %v", err)
+		}
+	})
+}
+
+func TestCopyObject(t *testing.T) {
+	c := New("bucket", "dest")
+	_, err := c.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:      aws.String("bucket"),
+		Key:         aws.String("key"),
+		Body:        bytes.NewReader([]byte("data")),
+		ContentType: aws.String("text/plain"),
+		Metadata:    map[string]string{"foo": "bar"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		directive           types.MetadataDirective
+		expectedContentType string
+		expectedMetadata    map[string]string
+	}{
+		"Copy": {
+			expectedContentType: "text/plain",
+			expectedMetadata:    map[string]string{"foo": "bar"},
+		},
+		"Replace": {
+			directive:           types.MetadataDirectiveReplace,
+			expectedContentType: "application/json",
+			expectedMetadata:    map[string]string{"baz": "qux"},
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			_, err := c.CopyObject(context.Background(), &s3.CopyObjectInput{
+				Bucket:            aws.String("dest"),
+				Key:               aws.String(name),
+				CopySource:        aws.String("bucket/key"),
+				MetadataDirective: tt.directive,
+				ContentType:       aws.String("application/json"),
+				Metadata:          map[string]string{"baz": "qux"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := c.GetObject(context.Background(), &s3.GetObjectInput{
+				Bucket: aws.String("dest"),
+				Key:    aws.String(name),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Body.Close()
+			if data, _ := io.ReadAll(out.Body); string(data) != "data" {
+				t.Errorf("Expected %q, got %q", "data", string(data))
+			}
+			if ct := aws.ToString(out.ContentType); ct != tt.expectedContentType {
+				t.Errorf("Expected content type %s, got %s", tt.expectedContentType, ct)
+			}
+			if !reflect.DeepEqual(tt.expectedMetadata, out.Metadata) {
+				t.Errorf("Expected metadata %v, got %v", tt.expectedMetadata, out.Metadata)
+			}
+		})
+	}
+}
+
+func TestDeleteObject(t *testing.T) {
+	c := New("bucket")
+	put(t, c, "key", []byte("data"))
+
+	for i := 0; i < 2; i++ {
+		// Deleting the non-existent key also succeeds.
+		if _, err := c.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+			Bucket: aws.String("bucket"),
+			Key:    aws.String("key"),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := c.HeadObject(context.Background(), &s3.HeadObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	var notFound *types.NotFound
+	if !errors.As(err, &notFound) {
+		t.Errorf("NotFound is expected, got %v", err)
+	}
+}
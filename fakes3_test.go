@@ -0,0 +1,686 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/seqsense/s3sync/v2/fakes3"
+)
+
+var _ S3API = (*fakes3.Client)(nil)
+
+func putFakeObject(t *testing.T, c *fakes3.Client, bucket, key string, data []byte) {
+	t.Helper()
+	_, err := c.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func fakeObjectKeys(t *testing.T, c *fakes3.Client, bucket string) []string {
+	t.Helper()
+	var keys []string
+	p := s3.NewListObjectsV2Paginator(c, &s3.ListObjectsV2Input{Bucket: &bucket})
+	for p.HasMorePages() {
+		out, err := p.NextPage(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, o := range out.Contents {
+			keys = append(keys, *o.Key)
+		}
+	}
+	return keys
+}
+
+// assertRoundTripNoop syncs the local directory to the S3 URL and back, and
+// checks that the up to date files are neither transferred nor deleted.
+func assertRoundTripNoop(t *testing.T, c *fakes3.Client, dir, url string, opts ...Option) {
+	t.Helper()
+	for _, pair := range [][2]string{{dir, url}, {url, dir}} {
+		m := NewWithClient(c, opts...)
+		if err := m.Sync(context.Background(), pair[0], pair[1]); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if s := m.GetStatistics(); s.Files != 0 || s.DeletedFiles != 0 {
+			t.Errorf("%s to %s: up to date files should not be synced, but %d files are synced and %d files are deleted",
+				pair[0], pair[1], s.Files, s.DeletedFiles)
+		}
+	}
+}
+
+func TestSyncWithFakeS3(t *testing.T) {
+	largeData := bytes.Repeat([]byte("0123456789abcdef"), 1024*1024) // 16MiB
+
+	t.Run("S3ToLocal", func(t *testing.T) {
+		c := fakes3.New("bucket")
+		putFakeObject(t, c, "bucket", "dir/foo", []byte("foo"))
+		putFakeObject(t, c, "bucket", "dir/bar/baz", largeData)
+		putFakeObject(t, c, "bucket", "other", []byte("other"))
+
+		temp := t.TempDir()
+		m := NewWithClient(c)
+		if err := m.Sync(context.Background(), "s3://bucket/dir", temp); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		fileHasSize(t, filepath.Join(temp, "foo"), 3)
+		fileHasSize(t, filepath.Join(temp, "bar", "baz"), len(largeData))
+		if _, err := os.Stat(filepath.Join(temp, "other")); !os.IsNotExist(err) {
+			t.Error("Object out of the prefix should not be downloaded")
+		}
+		if stats := m.GetStatistics(); stats.Files != 2 || stats.Bytes != int64(3+len(largeData)) {
+			t.Errorf("Unexpected statistics: files %d, bytes %d", stats.Files, stats.Bytes)
+		}
+	})
+	t.Run("LocalToS3", func(t *testing.T) {
+		c := fakes3.New("bucket")
+		putFakeObject(t, c, "bucket", "dest_only", []byte("dest_only"))
+
+		temp := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(temp, "bar"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(temp, "foo"), []byte("foo"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(temp, "bar", "baz"), largeData, 0644); err != nil {
+			t.Fatal(err)
+		}
+		// S3 truncates LastModified to seconds.
+		past := time.Now().Add(-time.Minute)
+		for _, file := range []string{"foo", "bar/baz"} {
+			if err := os.Chtimes(filepath.Join(temp, file), past, past); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		m := NewWithClient(c, WithDelete())
+		if err := m.Sync(context.Background(), temp, "s3://bucket"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if keys := fakeObjectKeys(t, c, "bucket"); !reflect.DeepEqual([]string{"bar/baz", "foo"}, keys) {
+			t.Errorf("Unexpected objects: %v", keys)
+		}
+		if stats := m.GetStatistics(); stats.Files != 2 || stats.DeletedFiles != 1 {
+			t.Errorf("Unexpected statistics: files %d, deleted %d", stats.Files, stats.DeletedFiles)
+		}
+
+		// Second sync doesn't upload anything.
+		m = NewWithClient(c, WithDelete())
+		if err := m.Sync(context.Background(), temp, "s3://bucket"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if n := m.GetStatistics().Files; n != 0 {
+			t.Errorf("Up to date files should not be uploaded, but %d files are uploaded", n)
+		}
+	})
+	t.Run("S3ToS3", func(t *testing.T) {
+		c := fakes3.New("source", "dest")
+		putFakeObject(t, c, "source", "foo", []byte("foo"))
+		putFakeObject(t, c, "source", "large", largeData)
+
+		m := NewWithClient(c, WithCopyThreshold(8*1024*1024), WithCopyPartSize(5*1024*1024))
+		if err := m.Sync(context.Background(), "s3://source", "s3://dest/copied"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if keys := fakeObjectKeys(t, c, "dest"); !reflect.DeepEqual([]string{"copied/foo", "copied/large"}, keys) {
+			t.Errorf("Unexpected objects: %v", keys)
+		}
+		head, err := c.HeadObject(context.Background(), &s3.HeadObjectInput{
+			Bucket:     aws.String("dest"),
+			Key:        aws.String("copied/large"),
+			PartNumber: aws.Int32(1),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := aws.ToInt32(head.PartsCount); n != 4 {
+			t.Errorf("Large object should be copied by 4 parts, got %d", n)
+		}
+	})
+	t.Run("Plan", func(t *testing.T) {
+		c := fakes3.New("bucket")
+		putFakeObject(t, c, "bucket", "foo", []byte("foo"))
+		putFakeObject(t, c, "bucket", "bar", []byte("bar"))
+
+		temp := t.TempDir()
+		m := NewWithClient(c)
+		plan, err := m.Plan(context.Background(), "s3://bucket", temp)
+		if err != nil {
+			t.Fatal("Plan should be successful", err)
+		}
+		if len(plan.Operations) != 2 {
+			t.Fatalf("Expected 2 operations, got %v", plan.Operations)
+		}
+		if err := NewWithClient(c).ExecutePlan(context.Background(), plan); err != errForeignPlan {
+			t.Errorf("Expected %v, got %v", errForeignPlan, err)
+		}
+
+		// Operations removed from the plan are not executed.
+		plan.Operations = plan.Operations[:1]
+		// Exported fields are informational.
+		plan.Operations[0].Type = OperationDelete
+		if err := m.ExecutePlan(context.Background(), plan); err != nil {
+			t.Fatal("ExecutePlan should be successful", err)
+		}
+		fileHasSize(t, filepath.Join(temp, "bar"), 3)
+		if _, err := os.Stat(filepath.Join(temp, "foo")); !os.IsNotExist(err) {
+			t.Error("Removed operation should not be executed")
+		}
+	})
+	t.Run("KeepPartial", func(t *testing.T) {
+		c := fakes3.New("bucket")
+		putFakeObject(t, c, "bucket", "foo", []byte("0123456789"))
+		head, err := c.HeadObject(context.Background(), &s3.HeadObjectInput{
+			Bucket: aws.String("bucket"),
+			Key:    aws.String("foo"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		temp := t.TempDir()
+		partial := filepath.Join(temp, "foo"+partialSuffix)
+		if err := os.WriteFile(partial, []byte("01234"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(partial, *head.LastModified, *head.LastModified); err != nil {
+			t.Fatal(err)
+		}
+		o := &recordingObserver{}
+		if err := NewWithClient(c, WithKeepPartial(), WithObserver(o)).Sync(context.Background(), "s3://bucket", temp); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if data, err := os.ReadFile(filepath.Join(temp, "foo")); err != nil || string(data) != "0123456789" {
+			t.Errorf("Unexpected contents: %q, %v", data, err)
+		}
+		if reports := o.reports[filepath.Join(temp, "foo")]; len(reports) == 0 || reports[0] != 5 || reports[len(reports)-1] != 10 {
+			t.Errorf("Progress of the resumed download should start at the offset, got %v", reports)
+		}
+
+		// The files having the suffix are synced unless the partial files are kept.
+		if err := os.WriteFile(partial, []byte("user file"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := NewWithClient(c, WithKeepPartial()).Sync(context.Background(), temp, "s3://bucket"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if keys := fakeObjectKeys(t, c, "bucket"); !reflect.DeepEqual([]string{"foo"}, keys) {
+			t.Errorf("Partial file should not be uploaded, got %v", keys)
+		}
+		if err := NewWithClient(c).Sync(context.Background(), temp, "s3://bucket"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if keys := fakeObjectKeys(t, c, "bucket"); !reflect.DeepEqual([]string{"foo", "foo" + partialSuffix}, keys) {
+			t.Errorf("File having the suffix should be uploaded, got %v", keys)
+		}
+	})
+	t.Run("ObjectAttributes", func(t *testing.T) {
+		c := fakes3.New("bucket")
+		temp := t.TempDir()
+		for _, file := range []string{"index.html", "main.js"} {
+			if err := os.WriteFile(filepath.Join(temp, file), []byte(file), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		m := NewWithClient(c,
+			WithCacheControl("max-age=60"),
+			WithMetadata(map[string]string{"foo": "bar"}),
+			WithStorageClass(types.StorageClassStandardIa),
+			WithTagging(map[string]string{"project": "s3sync"}),
+			WithAttributesFunc(func(name string, attrs *ObjectAttributes) {
+				if name == "index.html" {
+					attrs.CacheControl = aws.String("no-cache")
+				}
+			}),
+		)
+		if err := m.Sync(context.Background(), temp, "s3://bucket/src"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		for key, expected := range map[string]string{"src/index.html": "no-cache", "src/main.js": "max-age=60"} {
+			head, err := c.HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String(key)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cc := aws.ToString(head.CacheControl); cc != expected {
+				t.Errorf("%s: expected Cache-Control %s, got %s", key, expected, cc)
+			}
+			if !reflect.DeepEqual(map[string]string{"foo": "bar"}, head.Metadata) {
+				t.Errorf("%s: unexpected metadata %v", key, head.Metadata)
+			}
+			if head.StorageClass != types.StorageClassStandardIa {
+				t.Errorf("%s: unexpected storage class %s", key, head.StorageClass)
+			}
+			if n := aws.ToInt32(head.TagCount); n != 1 {
+				t.Errorf("%s: expected 1 tag, got %d", key, n)
+			}
+		}
+
+		testCases := map[string]struct {
+			opts                 []Option
+			expectedCacheControl string
+			expectedMetadata     map[string]string
+			expectedTagCount     int32
+		}{
+			"Copy": {
+				opts:                 []Option{WithCacheControl("max-age=300")},
+				expectedCacheControl: "no-cache",
+				expectedMetadata:     map[string]string{"foo": "bar"},
+				expectedTagCount:     1,
+			},
+			"Replace": {
+				opts:                 []Option{WithCacheControl("max-age=300"), WithReplaceMetadata()},
+				expectedCacheControl: "max-age=300",
+			},
+		}
+		for name, tt := range testCases {
+			tt := tt
+			t.Run(name, func(t *testing.T) {
+				for _, threshold := range []int64{DefaultCopyThreshold, 1} {
+					m := NewWithClient(c, append(tt.opts, WithCopyThreshold(threshold))...)
+					if err := m.Sync(context.Background(), "s3://bucket/src/index.html", "s3://bucket/"+name+"/"); err != nil {
+						t.Fatal("Sync should be successful", err)
+					}
+					head, err := c.HeadObject(context.Background(), &s3.HeadObjectInput{
+						Bucket: aws.String("bucket"),
+						Key:    aws.String(name + "/index.html"),
+					})
+					if err != nil {
+						t.Fatal(err)
+					}
+					if cc := aws.ToString(head.CacheControl); cc != tt.expectedCacheControl {
+						t.Errorf("Threshold %d: expected Cache-Control %s, got %s", threshold, tt.expectedCacheControl, cc)
+					}
+					if !reflect.DeepEqual(tt.expectedMetadata, head.Metadata) {
+						t.Errorf("Threshold %d: unexpected metadata %v", threshold, head.Metadata)
+					}
+					if ct := aws.ToString(head.ContentType); ct != "text/plain; charset=utf-8" {
+						t.Errorf("Threshold %d: content type should be kept, got %s", threshold, ct)
+					}
+					if n := aws.ToInt32(head.TagCount); n != tt.expectedTagCount {
+						t.Errorf("Threshold %d: expected %d tags, got %d", threshold, tt.expectedTagCount, n)
+					}
+					if head.StorageClass != types.StorageClassStandardIa {
+						t.Errorf("Threshold %d: storage class should be kept, got %s", threshold, head.StorageClass)
+					}
+				}
+			})
+		}
+	})
+	t.Run("ServerSideEncryption", func(t *testing.T) {
+		c := fakes3.New("bucket")
+		temp := t.TempDir()
+		if err := os.WriteFile(filepath.Join(temp, "foo"), []byte("foo"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(temp, "large"), largeData, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		sse := WithServerSideEncryption(ServerSideEncryption{CustomerKey: bytes.Repeat([]byte{1}, 32)})
+		if err := NewWithClient(c, sse).Sync(context.Background(), temp, "s3://bucket/src"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if err := NewWithClient(c).Sync(context.Background(), "s3://bucket/src", t.TempDir()); err == nil {
+			t.Error("Objects encrypted by SSE-C should not be downloaded without the key")
+		}
+		if err := NewWithClient(c, sse, WithCopyThreshold(8*1024*1024)).Sync(context.Background(), "s3://bucket/src", "s3://bucket/copied"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		dest := t.TempDir()
+		if err := NewWithClient(c, sse).Sync(context.Background(), "s3://bucket/copied", dest); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		fileHasSize(t, filepath.Join(dest, "foo"), 3)
+		fileHasSize(t, filepath.Join(dest, "large"), len(largeData))
+
+		kms := WithServerSideEncryption(ServerSideEncryption{
+			Type:     types.ServerSideEncryptionAwsKms,
+			KMSKeyID: "key-id",
+		})
+		if err := NewWithClient(c, kms).Sync(context.Background(), temp+"/foo", "s3://bucket/kms/"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		head, err := c.HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String("kms/foo")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if head.ServerSideEncryption != types.ServerSideEncryptionAwsKms || aws.ToString(head.SSEKMSKeyId) != "key-id" {
+			t.Errorf("Unexpected encryption: %s, %s", head.ServerSideEncryption, aws.ToString(head.SSEKMSKeyId))
+		}
+	})
+	t.Run("ClientSideEncryption", func(t *testing.T) {
+		c := fakes3.New("bucket")
+		keyProvider, err := NewAESKeyProvider(bytes.Repeat([]byte{1}, 32))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cse := WithClientSideEncryption(keyProvider)
+
+		temp := t.TempDir()
+		mtime := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+		for _, file := range []string{"foo", "large"} {
+			data := []byte(file)
+			if file == "large" {
+				data = largeData
+			}
+			if err := os.WriteFile(filepath.Join(temp, file), data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(filepath.Join(temp, file), mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := NewWithClient(c, cse).Sync(context.Background(), temp, "s3://bucket/src"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		out, err := c.GetObject(context.Background(), &s3.GetObjectInput{Bucket: aws.String("bucket"), Key: aws.String("src/foo")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if aws.ToInt64(out.ContentLength) == 3 || out.Metadata[metaSize] != "3" {
+			t.Errorf("Unexpected object: size %d, metadata %v", aws.ToInt64(out.ContentLength), out.Metadata)
+		}
+
+		// Encrypted objects are up to date although the object size differs.
+		m := NewWithClient(c, cse)
+		if err := m.Sync(context.Background(), temp, "s3://bucket/src"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if n := m.GetStatistics().Files; n != 0 {
+			t.Errorf("Up to date files should not be uploaded, but %d files are uploaded", n)
+		}
+
+		if err := NewWithClient(c, cse, WithReplaceMetadata(), WithCopyThreshold(8*1024*1024)).Sync(context.Background(), "s3://bucket/src", "s3://bucket/copied"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		dest := t.TempDir()
+		m = NewWithClient(c, cse)
+		if err := m.Sync(context.Background(), "s3://bucket/copied", dest); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		for file, size := range map[string]int{"foo": 3, "large": len(largeData)} {
+			stat, err := os.Stat(filepath.Join(dest, file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stat.Size() != int64(size) || !stat.ModTime().Equal(mtime) {
+				t.Errorf("%s: unexpected size %d, mtime %v", file, stat.Size(), stat.ModTime())
+			}
+		}
+		if data, err := os.ReadFile(filepath.Join(dest, "large")); err != nil || !bytes.Equal(largeData, data) {
+			t.Errorf("Decrypted data differs: %v", err)
+		}
+		if stats := m.GetStatistics(); stats.Files != 2 || stats.Bytes != int64(3+len(largeData)) {
+			t.Errorf("Unexpected statistics: files %d, bytes %d", stats.Files, stats.Bytes)
+		}
+	})
+	t.Run("Compression", func(t *testing.T) {
+		keyProvider, err := NewAESKeyProvider(bytes.Repeat([]byte{1}, 32))
+		if err != nil {
+			t.Fatal(err)
+		}
+		temp := t.TempDir()
+		mtime := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+		if err := os.WriteFile(filepath.Join(temp, "large"), largeData, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(filepath.Join(temp, "large"), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+
+		testCases := map[string]struct {
+			opts                    []Option
+			expectedContentEncoding string
+		}{
+			"Gzip": {
+				opts:                    []Option{WithCompression(CompressionGzip)},
+				expectedContentEncoding: "gzip",
+			},
+			"Zstd": {
+				opts:                    []Option{WithCompression(CompressionZstd)},
+				expectedContentEncoding: "zstd",
+			},
+			"ZstdWithEncryption": {
+				opts: []Option{WithCompression(CompressionZstd), WithClientSideEncryption(keyProvider)},
+			},
+		}
+		for name, tt := range testCases {
+			tt := tt
+			t.Run(name, func(t *testing.T) {
+				c := fakes3.New("bucket")
+				if err := NewWithClient(c, tt.opts...).Sync(context.Background(), temp, "s3://bucket"); err != nil {
+					t.Fatal("Sync should be successful", err)
+				}
+				head, err := c.HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String("large")})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if size := aws.ToInt64(head.ContentLength); size >= int64(len(largeData)) {
+					t.Errorf("Object is not compressed: %d bytes", size)
+				}
+				if ce := aws.ToString(head.ContentEncoding); ce != tt.expectedContentEncoding {
+					t.Errorf("Expected Content-Encoding %q, got %q", tt.expectedContentEncoding, ce)
+				}
+
+				m := NewWithClient(c, tt.opts...)
+				if err := m.Sync(context.Background(), temp, "s3://bucket"); err != nil {
+					t.Fatal("Sync should be successful", err)
+				}
+				if n := m.GetStatistics().Files; n != 0 {
+					t.Errorf("Up to date files should not be uploaded, but %d files are uploaded", n)
+				}
+
+				// WithCompression also decompresses the downloaded objects.
+				for _, opts := range [][]Option{tt.opts, append(tt.opts, WithDecompression())} {
+					dest := t.TempDir()
+					for i := 0; i < 2; i++ {
+						m = NewWithClient(c, opts...)
+						if err := m.Sync(context.Background(), "s3://bucket", dest); err != nil {
+							t.Fatal("Sync should be successful", err)
+						}
+					}
+					if n := m.GetStatistics().Files; n != 0 {
+						t.Errorf("Up to date files should not be downloaded, but %d files are downloaded", n)
+					}
+					data, err := os.ReadFile(filepath.Join(dest, "large"))
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !bytes.Equal(largeData, data) {
+						t.Error("Decompressed data differs")
+					}
+					if stat, err := os.Stat(filepath.Join(dest, "large")); err != nil || !stat.ModTime().Equal(mtime) {
+						t.Errorf("Modification time should be restored: %v", err)
+					}
+				}
+			})
+		}
+	})
+	t.Run("PreserveAttributes", func(t *testing.T) {
+		c := fakes3.New("bucket")
+		temp := t.TempDir()
+		mtime := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+		if err := os.WriteFile(filepath.Join(temp, "foo"), []byte("foo"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(filepath.Join(temp, "foo"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(filepath.Join(temp, "foo"), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+
+		preserve := []Option{WithPreserveModTime(), WithPreserveMode()}
+		if err := NewWithClient(c, preserve...).Sync(context.Background(), temp, "s3://bucket"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+
+		dest := t.TempDir()
+		if err := NewWithClient(c, preserve...).Sync(context.Background(), "s3://bucket", dest); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		stat, err := os.Stat(filepath.Join(dest, "foo"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !stat.ModTime().Equal(mtime) || stat.Mode().Perm() != 0600 {
+			t.Errorf("Attributes should be restored: mtime %v, mode %v", stat.ModTime(), stat.Mode())
+		}
+
+		// Round trip doesn't transfer the files.
+		assertRoundTripNoop(t, c, dest, "s3://bucket", preserve...)
+
+		// Upload time is used without the option.
+		dest = t.TempDir()
+		if err := NewWithClient(c).Sync(context.Background(), "s3://bucket", dest); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if stat, err := os.Stat(filepath.Join(dest, "foo")); err != nil || stat.ModTime().Equal(mtime) {
+			t.Errorf("Modification time should be the upload time: %v", err)
+		}
+	})
+	t.Run("Symlinks", func(t *testing.T) {
+		c := fakes3.New("bucket")
+		temp := t.TempDir()
+		if err := os.WriteFile(filepath.Join(temp, "foo"), []byte("foo"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink("foo", filepath.Join(temp, "link")); err != nil {
+			t.Fatal(err)
+		}
+
+		opt := WithSymlinks(SymlinksPreserve)
+		if err := NewWithClient(c, opt).Sync(context.Background(), temp, "s3://bucket"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		out, err := c.HeadObject(context.Background(), &s3.HeadObjectInput{
+			Bucket: aws.String("bucket"),
+			Key:    aws.String("link"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if aws.ToInt64(out.ContentLength) != 3 || out.Metadata[metaSymlink] == "" {
+			t.Errorf("Link target should be uploaded: size %d, metadata %v", aws.ToInt64(out.ContentLength), out.Metadata)
+		}
+
+		dest := t.TempDir()
+		if err := NewWithClient(c, opt).Sync(context.Background(), "s3://bucket", dest); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if target, err := os.Readlink(filepath.Join(dest, "link")); err != nil || target != "foo" {
+			t.Errorf("Symlink should be recreated: %q, %v", target, err)
+		}
+
+		// Round trip doesn't transfer the files.
+		assertRoundTripNoop(t, c, dest, "s3://bucket", opt)
+	})
+	t.Run("SymlinkEscape", func(t *testing.T) {
+		c := fakes3.New("bucket")
+		outside := t.TempDir()
+		for key, target := range map[string]string{"d/a": outside, "d/b": "../../escape"} {
+			_, err := c.PutObject(context.Background(), &s3.PutObjectInput{
+				Bucket:   aws.String("bucket"),
+				Key:      aws.String(key),
+				Body:     bytes.NewReader([]byte(target)),
+				Metadata: map[string]string{metaSymlink: "true"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		putFakeObject(t, c, "bucket", "d/a/pwned", []byte("pwned"))
+		putFakeObject(t, c, "bucket", "d/c/pwned", []byte("pwned"))
+
+		dest := t.TempDir()
+		// The link recreated by the previous sync.
+		if err := os.MkdirAll(filepath.Join(dest, "d"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(outside, filepath.Join(dest, "d", "c")); err != nil {
+			t.Fatal(err)
+		}
+
+		err := NewWithClient(c, WithSymlinks(SymlinksPreserve)).Sync(context.Background(), "s3://bucket", dest)
+		var se *SyncError
+		if !errors.As(err, &se) || len(se.Errors) != 4 {
+			t.Fatalf("Expected errors of the all files, got %v", err)
+		}
+		if entries, err := os.ReadDir(outside); err != nil || len(entries) != 0 {
+			t.Errorf("Files should not be written outside of the dest: %v, %v", entries, err)
+		}
+		for _, name := range []string{"a", "b"} {
+			if _, err := os.Lstat(filepath.Join(dest, "d", name)); !os.IsNotExist(err) {
+				t.Errorf("Symlink %s should not be created: %v", name, err)
+			}
+		}
+	})
+	t.Run("KeyOrder", func(t *testing.T) {
+		c := fakes3.New("bucket")
+		for _, key := range []string{"dir/a/b", "dir/a-b", "dir/a.b", "dir/a0", "dir-x/foo", "dir.x"} {
+			putFakeObject(t, c, "bucket", key, []byte(key))
+		}
+
+		temp := t.TempDir()
+		m := NewWithClient(c, WithDelete())
+		if err := m.Sync(context.Background(), "s3://bucket/dir", temp); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		// The objects having the prefix are synced as well as the ones under
+		// the directory, though they precede or follow the directory in order of the keys.
+		if n := m.GetStatistics().Files; n != 6 {
+			t.Errorf("All objects having the prefix should be synced, but %d files are synced", n)
+		}
+		for _, name := range []string{"-x/foo", ".x"} {
+			fileHasSize(t, filepath.Join(temp, filepath.FromSlash(name)), len("dir"+name))
+		}
+
+		// Round trip doesn't transfer nor delete the files.
+		assertRoundTripNoop(t, c, temp, "s3://bucket/dir", WithDelete())
+	})
+	t.Run("Pagination", func(t *testing.T) {
+		c := fakes3.New("bucket")
+		n := fakes3.DefaultMaxKeys + 10
+		for i := 0; i < n; i++ {
+			putFakeObject(t, c, "bucket", fmt.Sprintf("%04d", i), []byte{byte(i)})
+		}
+
+		temp := t.TempDir()
+		m := NewWithClient(c)
+		if err := m.Sync(context.Background(), "s3://bucket", temp); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if files := m.GetStatistics().Files; files != int64(n) {
+			t.Errorf("Expected %d files, got %d", n, files)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// pathFilter is an include or exclude rule of the files.
+type pathFilter struct {
+	pattern *regexp.Regexp
+	exclude bool
+}
+
+func newPathFilter(pattern string, exclude bool) pathFilter {
+	return pathFilter{
+		pattern: globToRegexp(pattern),
+		exclude: exclude,
+	}
+}
+
+// isExcluded returns true if the file is excluded by the filters.
+// Filters are evaluated in order and the last matched one wins.
+// Files not matching any filter are included.
+func (m *Manager) isExcluded(name string) bool {
+	name = filepath.ToSlash(name)
+	excluded := false
+	for _, f := range m.filters {
+		if f.pattern.MatchString(name) {
+			excluded = f.exclude
+		}
+	}
+	return excluded
+}
+
+// globToRegexp converts the glob pattern to the regular expression in the same manner as
+// Python's fnmatch used by aws-cli.
+// "*" matches any sequence of characters including "/", "?" matches any single character,
+// "[seq]" matches any character in seq and "[!seq]" matches any character not in seq.
+func globToRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			class, n := globCharClass(pattern[i:])
+			if n == 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			b.WriteString(class)
+			i += n - 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile("(?s)" + b.String())
+}
+
+// globCharClass converts the bracket expression at the beginning of s to the
+// regular expression and returns it with the number of consumed bytes.
+// It returns 0 if s doesn't start with a valid bracket expression.
+func globCharClass(s string) (string, int) {
+	j := 1
+	if j < len(s) && s[j] == '!' {
+		j++
+	}
+	if j < len(s) && s[j] == ']' {
+		j++
+	}
+	end := strings.IndexByte(s[j:], ']')
+	if end < 0 {
+		return "", 0
+	}
+	end += j
+
+	body := s[1:end]
+	negate := false
+	if strings.HasPrefix(body, "!") {
+		negate = true
+		body = body[1:]
+	}
+	body = strings.NewReplacer(`\`, `\\`, `[`, `\[`, `^`, `\^`).Replace(body)
+	if strings.HasPrefix(body, "]") {
+		body = `\` + body
+	}
+	class := "["
+	if negate {
+		class += "^"
+	}
+	class += body + "]"
+	if _, err := regexp.Compile(class); err != nil {
+		// e.g. invalid range like [z-a]
+		return "", 0
+	}
+	return class, end + 1
+}
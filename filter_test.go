@@ -0,0 +1,95 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"testing"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"*.js", "app.js", true},
+		{"*.js", "dir/app.js", true},
+		{"*.js", "app.json", false},
+		{"dir/*", "dir/sub/file", true},
+		{"dir/*", "other/file", false},
+		{"file?.txt", "file1.txt", true},
+		{"file?.txt", "file10.txt", false},
+		{"file[0-9].txt", "file5.txt", true},
+		{"file[!0-9].txt", "file5.txt", false},
+		{"file[!0-9].txt", "fileA.txt", true},
+		{"file[]].txt", "file].txt", true},
+		{"file[.txt", "file[.txt", true},
+		{"file[z-a].txt", "file[z-a].txt", true},
+		{"a.b", "axb", false},
+		{"(a|b)", "(a|b)", true},
+		{"[^a]", "^", true},
+		{"[^a]", "b", false},
+	}
+	for _, tt := range testCases {
+		if match := globToRegexp(tt.pattern).MatchString(tt.name); match != tt.match {
+			t.Errorf("Pattern %q against %q is expected to be %v, got %v", tt.pattern, tt.name, tt.match, match)
+		}
+	}
+}
+
+func TestIsExcluded(t *testing.T) {
+	testCases := map[string]struct {
+		options  []Option
+		excluded map[string]bool
+	}{
+		"NoFilter": {
+			excluded: map[string]bool{
+				"a.txt":     false,
+				"dir/b.txt": false,
+			},
+		},
+		"Exclude": {
+			options: []Option{WithExclude("*.txt")},
+			excluded: map[string]bool{
+				"a.txt":     true,
+				"dir/b.txt": true,
+				"c.md":      false,
+			},
+		},
+		"ExcludeThenInclude": {
+			options: []Option{WithExclude("*"), WithInclude("dir/*")},
+			excluded: map[string]bool{
+				"a.txt":     true,
+				"dir/b.txt": false,
+			},
+		},
+		"IncludeThenExclude": {
+			options: []Option{WithInclude("dir/*"), WithExclude("*")},
+			excluded: map[string]bool{
+				"a.txt":     true,
+				"dir/b.txt": true,
+			},
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			m := New(getSession(), tt.options...)
+			for name, expected := range tt.excluded {
+				if excluded := m.isExcluded(name); excluded != expected {
+					t.Errorf("%s is expected to be excluded=%v, got %v", name, expected, excluded)
+				}
+			}
+		})
+	}
+}
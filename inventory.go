@@ -0,0 +1,457 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"compress/gzip"
+	"container/heap"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Inventory is the S3 Inventory report used to list the objects of its source
+// bucket instead of ListObjectsV2.
+//
+// Only the reports in CSV format are supported. ORC and Parquet reports are rejected.
+// The report is used for both of the source and the dest buckets. The objects
+// to be synced or deleted by the report are requested by HeadObject to be
+// compared by their current states. The objects created after the report, and
+// the ones modified after the report but up to date by it, are synced by the
+// later reports.
+type Inventory struct {
+	// Manifest is the S3 URL of manifest.json of the report, e.g.
+	// "s3://inventory-bucket/prefix/source-bucket/config-id/2026-01-01T01-00Z/manifest.json".
+	Manifest string
+	// MaxAge is the freshness cutoff of the report. If the report is created more
+	// than MaxAge ago, the report is not read and the objects are listed by ListObjectsV2.
+	// Zero means that the report is always used.
+	MaxAge time.Duration
+}
+
+// inventoryManifest is the contents of manifest.json of the S3 Inventory report.
+type inventoryManifest struct {
+	SourceBucket      string `json:"sourceBucket"`
+	DestinationBucket string `json:"destinationBucket"`
+	CreationTimestamp string `json:"creationTimestamp"`
+	FileFormat        string `json:"fileFormat"`
+	FileSchema        string `json:"fileSchema"`
+	Files             []struct {
+		Key string `json:"key"`
+	} `json:"files"`
+}
+
+// inventoryReport is the S3 Inventory report to be read.
+type inventoryReport struct {
+	manifest string
+	// bucket is the bucket storing the report files.
+	bucket  string
+	files   []string
+	created time.Time
+	// columns maps the field names to the column indices.
+	columns map[string]int
+}
+
+var errInventoryNotSorted = errors.New("inventory file is not sorted by key")
+
+// openInventory returns the report of the inventory of the bucket of the path.
+// nil is returned if the inventory is not specified or is older than MaxAge.
+func (m *Manager) openInventory(ctx context.Context, path *s3Path) (*inventoryReport, error) {
+	if path == nil {
+		return nil, nil
+	}
+	for _, inv := range m.inventories {
+		report, sourceBucket, err := m.readInventoryManifest(ctx, inv.Manifest)
+		if err != nil {
+			return nil, &ListError{Path: inv.Manifest, Err: err}
+		}
+		if sourceBucket != path.bucket {
+			continue
+		}
+		if inv.MaxAge > 0 && time.Since(report.created) > inv.MaxAge {
+			m.log(LogEvent{
+				Level:   slog.LevelInfo,
+				Message: fmt.Sprintf("inventory report %s is older than %v, listing %s", inv.Manifest, inv.MaxAge, path),
+			}, true)
+			return nil, nil
+		}
+		return report, nil
+	}
+	return nil, nil
+}
+
+// readInventoryManifest reads the manifest and returns the report and its source bucket.
+func (m *Manager) readInventoryManifest(ctx context.Context, manifest string) (*inventoryReport, string, error) {
+	u, err := url.Parse(manifest)
+	if err != nil {
+		return nil, "", err
+	}
+	if u.Scheme != "s3" {
+		return nil, "", fmt.Errorf("manifest must be an S3 URL: %s", manifest)
+	}
+	path, err := urlToS3Path(u)
+	if err != nil {
+		return nil, "", err
+	}
+	out, err := m.s3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &path.bucket,
+		Key:    &path.bucketPrefix,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	defer out.Body.Close()
+
+	var mf inventoryManifest
+	if err := json.NewDecoder(out.Body).Decode(&mf); err != nil {
+		return nil, "", err
+	}
+	if mf.FileFormat != "CSV" {
+		return nil, "", fmt.Errorf("inventory file format %q is not supported", mf.FileFormat)
+	}
+	msec, err := strconv.ParseInt(mf.CreationTimestamp, 10, 64)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid creationTimestamp: %w", err)
+	}
+	report := &inventoryReport{
+		manifest: manifest,
+		bucket:   strings.TrimPrefix(mf.DestinationBucket, "arn:aws:s3:::"),
+		created:  time.UnixMilli(msec),
+		columns:  make(map[string]int),
+	}
+	if report.bucket == "" {
+		report.bucket = path.bucket
+	}
+	for i, name := range strings.Split(mf.FileSchema, ",") {
+		report.columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"Key", "Size", "LastModifiedDate"} {
+		if _, ok := report.columns[name]; !ok {
+			return nil, "", fmt.Errorf("inventory doesn't have %s field", name)
+		}
+	}
+	for _, f := range mf.Files {
+		report.files = append(report.files, f.Key)
+	}
+	return report, mf.SourceBucket, nil
+}
+
+// listInventoryFiles returns a channel which receives the file infos under the
+// given s3Path listed in the report.
+func (m *Manager) listInventoryFiles(ctx context.Context, path *s3Path, report *inventoryReport) chan *fileInfo {
+	return m.listPrefixFiles(ctx, path, func(path *s3Path) chan *fileInfo {
+		return m.listReportFiles(ctx, path, report)
+	})
+}
+
+// listReportFiles returns a channel which receives the file infos under the
+// given s3Path listed in the report.
+func (m *Manager) listReportFiles(ctx context.Context, path *s3Path, report *inventoryReport) chan *fileInfo {
+	c := make(chan *fileInfo, listBufferSize)
+
+	go func() {
+		defer close(c)
+		err := m.readInventory(ctx, report, func(object types.Object) error {
+			if !strings.HasPrefix(*object.Key, path.bucketPrefix) {
+				return nil
+			}
+			fi := m.s3FileInfo(path, object)
+			if fi == nil {
+				return nil
+			}
+			fi.inventoried = true
+			select {
+			case c <- fi:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil {
+			sendErrorInfoToChannel(ctx, c, &ListError{Path: report.manifest, Err: err})
+		}
+	}()
+
+	return c
+}
+
+// readInventory calls fn for each object in the report in order of the keys.
+// The rows of the report files sorted by the keys are merged.
+func (m *Manager) readInventory(ctx context.Context, report *inventoryReport, fn func(types.Object) error) error {
+	var readers inventoryReaders
+	defer func() {
+		for _, r := range readers {
+			r.Close()
+		}
+	}()
+	for _, key := range report.files {
+		r, err := m.openInventoryFile(ctx, report, key)
+		if err != nil {
+			return err
+		}
+		ok, err := r.next()
+		if err != nil {
+			r.Close()
+			return err
+		}
+		if !ok {
+			r.Close()
+			continue
+		}
+		readers = append(readers, r)
+	}
+	heap.Init(&readers)
+
+	for len(readers) > 0 {
+		r := readers[0]
+		if err := fn(r.object); err != nil {
+			return err
+		}
+		ok, err := r.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(&readers, 0)
+			continue
+		}
+		heap.Pop(&readers)
+		r.Close()
+	}
+	return nil
+}
+
+// inventoryReader reads the objects from a gzip compressed CSV inventory file.
+type inventoryReader struct {
+	io.Closer
+	key     string
+	report  *inventoryReport
+	csv     *csv.Reader
+	object  types.Object
+	lastKey string
+}
+
+func (m *Manager) openInventoryFile(ctx context.Context, report *inventoryReport, key string) (*inventoryReader, error) {
+	out, err := m.s3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &report.bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(out.Body)
+	if err != nil {
+		out.Body.Close()
+		return nil, err
+	}
+	r := csv.NewReader(zr)
+	r.FieldsPerRecord = -1
+	r.ReuseRecord = true
+	return &inventoryReader{Closer: out.Body, key: key, report: report, csv: r}, nil
+}
+
+// next reads the next object. It returns false at the end of the file.
+func (r *inventoryReader) next() (bool, error) {
+	for {
+		record, err := r.csv.Read()
+		if err == io.EOF {
+			return false, nil
+		} else if err != nil {
+			return false, fmt.Errorf("%s: %w", r.key, err)
+		}
+		object, ok, err := r.report.object(record)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", r.key, err)
+		}
+		if !ok {
+			continue
+		}
+		if *object.Key < r.lastKey {
+			return false, fmt.Errorf("%s: %w", r.key, errInventoryNotSorted)
+		}
+		r.object, r.lastKey = object, *object.Key
+		return true, nil
+	}
+}
+
+// object returns the object of the inventory row.
+// It returns false if the row is not the latest version of the object.
+func (r *inventoryReport) object(record []string) (types.Object, bool, error) {
+	field := func(name string) string {
+		i, ok := r.columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return record[i]
+	}
+	if field("IsLatest") == "false" || field("IsDeleteMarker") == "true" {
+		return types.Object{}, false, nil
+	}
+	key, err := url.QueryUnescape(field("Key"))
+	if err != nil {
+		return types.Object{}, false, err
+	}
+	size, err := strconv.ParseInt(field("Size"), 10, 64)
+	if err != nil {
+		return types.Object{}, false, err
+	}
+	lastModified, err := time.Parse(time.RFC3339, field("LastModifiedDate"))
+	if err != nil {
+		return types.Object{}, false, err
+	}
+	return types.Object{
+		Key:          aws.String(key),
+		Size:         aws.Int64(size),
+		LastModified: aws.Time(lastModified),
+		ETag:         aws.String(field("ETag")),
+		StorageClass: types.ObjectStorageClass(field("StorageClass")),
+	}, true, nil
+}
+
+// inventoryReaders is the min-heap of the readers ordered by the keys of the current objects.
+type inventoryReaders []*inventoryReader
+
+func (h inventoryReaders) Len() int           { return len(h) }
+func (h inventoryReaders) Less(i, j int) bool { return *h[i].object.Key < *h[j].object.Key }
+func (h inventoryReaders) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *inventoryReaders) Push(x any)        { *h = append(*h, x.(*inventoryReader)) }
+func (h *inventoryReaders) Pop() any {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
+
+// reconcileInventoryOps returns a channel which receives the operations updated
+// by the current states of the objects listed from the reports.
+// Only the objects of the operations to be run are requested, so that the objects
+// created after the reports and the skipped ones modified after the reports are
+// synced by the later reports.
+func (m *Manager) reconcileInventoryOps(ctx context.Context, p *syncPair, ops chan *fileOp, sourceListed, destListed bool, compare compareFunc) chan *fileOp {
+	c := make(chan *fileOp)
+
+	go func() {
+		defer close(c)
+		for op := range ops {
+			if op.err == nil && op.op != opSkip {
+				reconciled, err := m.reconcileOp(ctx, p, op, sourceListed, destListed, compare)
+				if err != nil {
+					failed := *op.fileInfo
+					failed.err = err
+					reconciled = &fileOp{fileInfo: &failed}
+				}
+				if reconciled == nil {
+					continue
+				}
+				op = reconciled
+			}
+			select {
+			case c <- op:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return c
+}
+
+// reconcileOp returns the operation updated by the current states of the objects,
+// or nil if the operation is no longer required.
+func (m *Manager) reconcileOp(ctx context.Context, p *syncPair, op *fileOp, sourceListed, destListed bool, compare compareFunc) (*fileOp, error) {
+	if op.op == opDelete {
+		if sourceListed {
+			// The source created after the report is not deleted.
+			source, err := m.headFile(ctx, p.sourceS3.bucket, &fileInfo{name: op.name, path: remoteTarget(op.fileInfo, p.sourceS3).bucketPrefix})
+			if err != nil || source != nil {
+				return nil, err
+			}
+		}
+		if !op.inventoried {
+			return op, nil
+		}
+		dest, err := m.headFile(ctx, p.destS3.bucket, op.fileInfo)
+		if err != nil || dest == nil {
+			return nil, err
+		}
+		return &fileOp{fileInfo: dest, op: opDelete, reason: op.reason}, nil
+	}
+
+	source := op.fileInfo
+	if source.inventoried {
+		var err error
+		if source, err = m.headFile(ctx, p.sourceS3.bucket, source); err != nil || source == nil {
+			// The source deleted after the report is deleted from the dest by the later sync.
+			return nil, err
+		}
+	}
+	dest := op.dest
+	var err error
+	switch {
+	case dest != nil && dest.inventoried:
+		dest, err = m.headFile(ctx, p.destS3.bucket, dest)
+	case dest == nil && destListed:
+		dest, err = m.headFile(ctx, p.destS3.bucket, &fileInfo{name: source.name, path: remoteTarget(source, p.destS3).bucketPrefix})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if dest == nil {
+		return &fileOp{fileInfo: source, reason: ReasonMissing}, nil
+	}
+	reason, err := compare(source, dest)
+	if err != nil {
+		return nil, err
+	}
+	if reason == "" {
+		return &fileOp{fileInfo: source, op: opSkip, dest: dest}, nil
+	}
+	return &fileOp{fileInfo: source, reason: reason, dest: dest}, nil
+}
+
+// headFile returns the file updated by the current state of its object,
+// or nil if the object doesn't exist.
+func (m *Manager) headFile(ctx context.Context, bucket string, file *fileInfo) (*fileInfo, error) {
+	input := &s3.HeadObjectInput{
+		Bucket: &bucket,
+		Key:    aws.String(file.objectKey()),
+	}
+	m.sse.headObject(input)
+	out, err := m.s3.HeadObject(ctx, input)
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	current := *file
+	current.size = aws.ToInt64(out.ContentLength)
+	current.lastModified = aws.ToTime(out.LastModified)
+	current.etag = strings.Trim(aws.ToString(out.ETag), `"`)
+	current.storageClass = types.ObjectStorageClass(out.StorageClass)
+	current.inventoried = false
+	return &current, nil
+}
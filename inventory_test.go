@@ -0,0 +1,283 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/seqsense/s3sync/v2/fakes3"
+)
+
+const testInventorySchema = "Bucket, Key, VersionId, IsLatest, IsDeleteMarker, Size, LastModifiedDate, ETag, StorageClass"
+
+// putInventory puts the manifest.json and the gzip compressed CSV files of the
+// inventory report of "bucket" to "inventory" bucket.
+func putInventory(t *testing.T, c *fakes3.Client, created time.Time, format string, files ...[]string) string {
+	t.Helper()
+	var keys []string
+	for i, rows := range files {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		for _, row := range rows {
+			fmt.Fprintln(zw, row)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		key := fmt.Sprintf("report/files/%d.csv.gz", i)
+		putFakeObject(t, c, "inventory", key, buf.Bytes())
+		keys = append(keys, fmt.Sprintf(`{"key": %q}`, key))
+	}
+	manifest := fmt.Sprintf(`{
+  "sourceBucket": "bucket",
+  "destinationBucket": "arn:aws:s3:::inventory",
+  "version": "2016-11-30",
+  "creationTimestamp": "%d",
+  "fileFormat": %q,
+  "fileSchema": %q,
+  "files": [%s]
+}`, created.UnixMilli(), format, testInventorySchema, strings.Join(keys, ","))
+	putFakeObject(t, c, "inventory", "report/manifest.json", []byte(manifest))
+	return "s3://inventory/report/manifest.json"
+}
+
+func inventoryRow(key string, size int, lastModified time.Time) string {
+	return fmt.Sprintf(`"bucket","%s","v1","true","false","%d","%s","etag-%s","STANDARD"`,
+		key, size, lastModified.UTC().Format("2006-01-02T15:04:05.000Z"), key)
+}
+
+func TestInventory(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	before := now.Add(-time.Hour)
+
+	newClient := func(t *testing.T) *fakes3.Client {
+		c := fakes3.New("bucket", "inventory")
+		c.Now = func() time.Time { return before }
+		for _, key := range []string{"dir/a b", "dir/b", "dir/c/d", "dir/deleted-later", "other"} {
+			putFakeObject(t, c, "bucket", key, []byte(key))
+		}
+		// Changed after the report.
+		c.Now = func() time.Time { return now.Add(time.Minute) }
+		putFakeObject(t, c, "bucket", "dir/new", []byte("dir/new"))
+		putFakeObject(t, c, "bucket", "dir/c/d", []byte("dir/c/d updated"))
+		if _, err := c.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+			Bucket: aws.String("bucket"),
+			Key:    aws.String("dir/deleted-later"),
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	files := [][]string{
+		{
+			inventoryRow("dir%2Fa+b", 7, before),
+			`"bucket","dir%2Fb","v0","false","false","1","2020-01-01T00:00:00.000Z","old","STANDARD"`,
+			inventoryRow("dir%2Fc%2Fd", 7, before),
+			inventoryRow("dir%2Fdeleted-later", 17, before),
+		},
+		{
+			inventoryRow("dir%2Fb", 5, before),
+			`"bucket","dir%2Fdeleted","v2","true","true","","2020-01-01T00:00:00.000Z","","STANDARD"`,
+			inventoryRow("other", 5, before),
+		},
+	}
+
+	t.Run("List", func(t *testing.T) {
+		c := newClient(t)
+		manifest := putInventory(t, c, now, "CSV", files...)
+		m := NewWithClient(c, WithInventory(Inventory{Manifest: manifest}))
+		path := &s3Path{bucket: "bucket", bucketPrefix: "dir"}
+		report, err := m.openInventory(context.Background(), path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report == nil {
+			t.Fatal("Inventory report should be used")
+		}
+		var listed []string
+		for fi := range m.listInventoryFiles(context.Background(), path, report) {
+			if fi.err != nil {
+				t.Fatal(fi.err)
+			}
+			listed = append(listed, fmt.Sprintf("%s %d %s %s", fi.name, fi.size, fi.etag, fi.storageClass))
+		}
+		// The objects changed after the report are listed as reported.
+		expected := []string{
+			"a b 7 etag-dir%2Fa+b STANDARD",
+			"b 5 etag-dir%2Fb STANDARD",
+			"c/d 7 etag-dir%2Fc%2Fd STANDARD",
+			"deleted-later 17 etag-dir%2Fdeleted-later STANDARD",
+		}
+		if !reflect.DeepEqual(expected, listed) {
+			t.Errorf("Expected %v, got %v", expected, listed)
+		}
+	})
+	t.Run("Sync", func(t *testing.T) {
+		c := newClient(t)
+		manifest := putInventory(t, c, now, "CSV", files...)
+		temp := t.TempDir()
+		m := NewWithClient(c, WithInventory(Inventory{Manifest: manifest, MaxAge: time.Hour}))
+		if err := m.Sync(context.Background(), "s3://bucket/dir", temp); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		// The reported objects are synced by the current states, and the object
+		// created after the report is synced by the later report.
+		if n := m.GetStatistics().Files; n != 3 {
+			t.Errorf("Expected 3 files to be synced, got %d", n)
+		}
+		fileHasSize(t, filepath.Join(temp, "c/d"), 15)
+		for _, name := range []string{"new", "deleted-later"} {
+			if _, err := os.Stat(filepath.Join(temp, name)); !os.IsNotExist(err) {
+				t.Errorf("%s should not be synced: %v", name, err)
+			}
+		}
+	})
+	t.Run("ListRequests", func(t *testing.T) {
+		c := &listCounter{Client: newClient(t)}
+		manifest := putInventory(t, c.Client, now, "CSV", files...)
+		m := NewWithClient(c, WithInventory(Inventory{Manifest: manifest}))
+		if err := m.Sync(context.Background(), "s3://bucket/dir/", t.TempDir()); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if c.total != 0 {
+			t.Errorf("Objects should not be listed by ListObjectsV2, but %d requests are sent", c.total)
+		}
+		if n := m.GetStatistics().Files; n != 3 {
+			t.Errorf("Expected 3 files to be synced, got %d", n)
+		}
+	})
+	t.Run("MaxAge", func(t *testing.T) {
+		c := &listCounter{Client: newClient(t)}
+		manifest := putInventory(t, c.Client, before, "CSV", files...)
+		temp := t.TempDir()
+		m := NewWithClient(c, WithInventory(Inventory{Manifest: manifest, MaxAge: time.Minute}))
+		if err := m.Sync(context.Background(), "s3://bucket/dir/", temp); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if n := m.GetStatistics().Files; n != 4 || c.total == 0 {
+			t.Errorf("Objects should be listed by ListObjectsV2, but %d files are synced by %d requests", n, c.total)
+		}
+	})
+	t.Run("Delete", func(t *testing.T) {
+		c := newClient(t)
+		manifest := putInventory(t, c, now, "CSV", files...)
+		temp := t.TempDir()
+		for name, mtime := range map[string]time.Time{
+			"a b":     now,
+			"b":       now,
+			"c/d":     now,
+			"new":     now.Add(time.Minute),
+			"removed": now.Add(time.Minute),
+			"old":     before,
+		} {
+			filename := filepath.Join(temp, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filename, []byte("dir/"+name), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(filename, mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+		}
+		m := NewWithClient(c, WithInventory(Inventory{Manifest: manifest}), WithDelete())
+		if err := m.Sync(context.Background(), "s3://bucket/dir", temp); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		// "new" is kept as the object is created after the report.
+		for name, exists := range map[string]bool{"new": true, "removed": false, "old": false} {
+			if _, err := os.Stat(filepath.Join(temp, name)); os.IsNotExist(err) == exists {
+				t.Errorf("%s: expected to exist %v, got %v", name, exists, err)
+			}
+		}
+	})
+	t.Run("Dest", func(t *testing.T) {
+		c := newClient(t)
+		manifest := putInventory(t, c, now, "CSV", files...)
+		temp := t.TempDir()
+		for name, data := range map[string]string{
+			"a b":           "dir/a b",
+			"c/d":           "dir/c/d updated",
+			"deleted-later": "dir/deleted-later updated",
+			"new":           "dir/new",
+		} {
+			filename := filepath.Join(temp, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			mtime := before.Add(-time.Hour)
+			if err := os.Chtimes(filename, mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+		}
+		m := NewWithClient(c, WithInventory(Inventory{Manifest: manifest}))
+		if err := m.Sync(context.Background(), temp, "s3://bucket/dir"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		// Only the object deleted after the report is synced, as the others
+		// are up to date by the current states.
+		if n := m.GetStatistics().Files; n != 1 {
+			t.Errorf("Expected 1 file to be synced, got %d", n)
+		}
+		if keys := fakeObjectKeys(t, c, "bucket"); !reflect.DeepEqual([]string{"dir/a b", "dir/b", "dir/c/d", "dir/deleted-later", "dir/new", "other"}, keys) {
+			t.Errorf("Unexpected objects: %v", keys)
+		}
+	})
+	t.Run("NotSorted", func(t *testing.T) {
+		c := newClient(t)
+		manifest := putInventory(t, c, now, "CSV", []string{
+			inventoryRow("dir%2Fb", 5, before),
+			inventoryRow("dir%2Fa+b", 7, before),
+		})
+		err := NewWithClient(c, WithInventory(Inventory{Manifest: manifest})).
+			Sync(context.Background(), "s3://bucket/dir", t.TempDir())
+		if !errors.Is(err, errInventoryNotSorted) {
+			t.Errorf("Expected %v, got %v", errInventoryNotSorted, err)
+		}
+	})
+	t.Run("UnsupportedFormat", func(t *testing.T) {
+		c := newClient(t)
+		manifest := putInventory(t, c, now, "Parquet")
+		err := NewWithClient(c, WithInventory(Inventory{Manifest: manifest})).
+			Sync(context.Background(), "s3://bucket/dir", t.TempDir())
+		var listErr *ListError
+		if !errors.As(err, &listErr) || !strings.Contains(err.Error(), "not supported") {
+			t.Errorf("Expected ListError of unsupported format, got %v", err)
+		}
+	})
+	t.Run("OtherBucket", func(t *testing.T) {
+		c := newClient(t)
+		manifest := putInventory(t, c, now, "CSV", files...)
+		m := NewWithClient(c, WithInventory(Inventory{Manifest: manifest}))
+		report, err := m.openInventory(context.Background(), &s3Path{bucket: "inventory"})
+		if err != nil || report != nil {
+			t.Errorf("Inventory of the other bucket should not be used: %v, %v", report, err)
+		}
+	})
+}
@@ -0,0 +1,476 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Types of the journal entries.
+const (
+	journalSync   = "sync"
+	journalDone   = "done"
+	journalUpload = "upload"
+	journalPart   = "part"
+	journalEnd    = "end"
+)
+
+// journalEntry is a line of the journal.
+type journalEntry struct {
+	Type string `json:"type"`
+	// Source and Dest are set on the sync entry at the head of the journal.
+	Source string `json:"source,omitempty"`
+	Dest   string `json:"dest,omitempty"`
+	// Name, Size and Mtime are the source file of the done and upload entries.
+	Name  string `json:"name,omitempty"`
+	Size  int64  `json:"size,omitempty"`
+	Mtime int64  `json:"mtime,omitempty"`
+	// UploadID is set on the upload, part and end entries.
+	UploadID string `json:"uploadId,omitempty"`
+	// Bucket, Key and PartSize are set on the upload entries.
+	Bucket   string `json:"bucket,omitempty"`
+	Key      string `json:"key,omitempty"`
+	PartSize int64  `json:"partSize,omitempty"`
+	// PartNumber and ETag are set on the part entries.
+	PartNumber int32  `json:"partNumber,omitempty"`
+	ETag       string `json:"etag,omitempty"`
+}
+
+// resumableUpload is the multipart upload in progress recorded in the journal.
+type resumableUpload struct {
+	journalEntry
+	// parts maps the part numbers to the ETags of the uploaded parts.
+	parts map[int32]string
+}
+
+// syncJournal is the checkpoint journal of the sync.
+// The completed operations and the multipart uploads in progress are appended
+// to the journal, so that the interrupted sync can be resumed by the next sync
+// with the same journal. The multipart upload ends with the end entry when it
+// is completed or aborted. The journal is removed when the sync is completed.
+// Only the multipart uploads in progress and the files completed by the
+// interrupted sync and not compared yet are kept in memory.
+// Methods are nil-safe so that they can be called without the journal.
+type syncJournal struct {
+	mu        sync.Mutex
+	f         *os.File
+	enc       *json.Encoder
+	err       error
+	doneFiles map[string]journalEntry
+	uploads   map[string]*resumableUpload
+}
+
+// openJournal opens the journal of the sync pair.
+// The journal is locked not to be written by the other syncs at the same time,
+// and the journal of the other sync pair is not opened to keep its multipart
+// uploads in progress.
+func (m *Manager) openJournal(p *syncPair) (*syncJournal, error) {
+	if err := os.MkdirAll(filepath.Dir(m.journal), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(m.journal, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("journal %s is used by another sync: %w", m.journal, err)
+	}
+	j := &syncJournal{
+		f:         f,
+		enc:       json.NewEncoder(f),
+		doneFiles: make(map[string]journalEntry),
+		uploads:   make(map[string]*resumableUpload),
+	}
+	header := journalEntry{Type: journalSync, Source: p.source, Dest: p.dest}
+
+	dec := json.NewDecoder(f)
+	var offset int64
+	var h journalEntry
+	switch err := dec.Decode(&h); {
+	case err == io.EOF:
+		// New journal.
+	case err != nil:
+		f.Close()
+		return nil, fmt.Errorf("invalid journal %s: %w", m.journal, err)
+	case h != header:
+		f.Close()
+		return nil, fmt.Errorf("journal %s is of another sync from %s to %s", m.journal, h.Source, h.Dest)
+	default:
+		offset = dec.InputOffset()
+		for {
+			var e journalEntry
+			if err := dec.Decode(&e); err != nil {
+				// The last entry may be partially written on interruption.
+				break
+			}
+			j.load(e)
+			offset = dec.InputOffset()
+		}
+	}
+	if err := f.Truncate(offset); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if offset == 0 {
+		if err := j.enc.Encode(&header); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return j, nil
+}
+
+// load applies the entry read from the journal.
+func (j *syncJournal) load(e journalEntry) {
+	switch e.Type {
+	case journalDone:
+		j.doneFiles[e.Name] = e
+	case journalUpload:
+		j.uploads[e.Name] = &resumableUpload{journalEntry: e, parts: make(map[int32]string)}
+	case journalPart:
+		if u, ok := j.uploads[e.Name]; ok && u.UploadID == e.UploadID {
+			u.parts[e.PartNumber] = e.ETag
+		}
+	case journalEnd:
+		if u, ok := j.uploads[e.Name]; ok && u.UploadID == e.UploadID {
+			delete(j.uploads, e.Name)
+		}
+	}
+}
+
+// append writes the entry to the journal and applies it.
+// The done entries are only written, as the completed files are not compared again.
+func (j *syncJournal) append(e journalEntry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.enc.Encode(&e); err != nil && j.err == nil {
+		j.err = err
+	}
+	if e.Type != journalDone {
+		j.load(e)
+	}
+}
+
+// compare returns the compareFunc skipping the files completed by the
+// interrupted sync without comparing them again.
+func (j *syncJournal) compare(compare compareFunc) compareFunc {
+	if j == nil {
+		return compare
+	}
+	return func(source, dest *fileInfo) (Reason, error) {
+		if j.done(source) {
+			return "", nil
+		}
+		return compare(source, dest)
+	}
+}
+
+// completed returns true if the operation is completed by the interrupted sync.
+func (j *syncJournal) completed(op *fileOp) bool {
+	return j != nil && op.op == opUpdate && j.done(op.fileInfo)
+}
+
+// done returns true if the file is completed by the interrupted sync.
+// The entry is forgotten as each file is compared once.
+func (j *syncJournal) done(file *fileInfo) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	name := filepath.ToSlash(file.name)
+	e, ok := j.doneFiles[name]
+	delete(j.doneFiles, name)
+	return ok && e.Size == file.size && e.Mtime == file.lastModified.UnixNano()
+}
+
+// complete records the completed operation.
+func (j *syncJournal) complete(op *fileOp) {
+	if j == nil || op.op != opUpdate {
+		return
+	}
+	j.append(journalEntry{
+		Type:  journalDone,
+		Name:  filepath.ToSlash(op.name),
+		Size:  op.size,
+		Mtime: op.lastModified.UnixNano(),
+	})
+}
+
+// upload returns the multipart upload of the file in progress, or nil if not found.
+func (j *syncJournal) upload(file *fileInfo) *resumableUpload {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	u, ok := j.uploads[filepath.ToSlash(file.name)]
+	if !ok {
+		return nil
+	}
+	// Copy the parts not to be modified while being read.
+	parts := make(map[int32]string, len(u.parts))
+	for n, etag := range u.parts {
+		parts[n] = etag
+	}
+	return &resumableUpload{journalEntry: u.journalEntry, parts: parts}
+}
+
+// end records the end of the multipart upload.
+func (j *syncJournal) end(name, uploadID string) {
+	j.append(journalEntry{Type: journalEnd, Name: name, UploadID: uploadID})
+}
+
+// finish removes the journal if the sync is completed, or closes it to be resumed.
+// On completion, the multipart uploads not ended, e.g. of the files removed
+// from the source after the interruption, are aborted. The journal is kept if
+// any of them fails to be aborted, so that it is retried by the next sync.
+func (j *syncJournal) finish(ctx context.Context, api S3API, completed bool) error {
+	if j == nil {
+		return nil
+	}
+	if !completed {
+		if err := j.f.Close(); err != nil {
+			return err
+		}
+		return j.err
+	}
+	errs := &multiErr{}
+	for name, u := range j.uploads {
+		_, err := api.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   &u.Bucket,
+			Key:      &u.Key,
+			UploadId: &u.UploadID,
+		})
+		var noSuchUpload *types.NoSuchUpload
+		if err != nil && !errors.As(err, &noSuchUpload) {
+			errs.Append(err)
+			continue
+		}
+		j.end(name, u.UploadID)
+	}
+	if err := j.f.Close(); err != nil {
+		return err
+	}
+	if err := errs.ErrOrNil(); err != nil {
+		return err
+	}
+	return os.Remove(j.f.Name())
+}
+
+// uploadMultipart uploads the file by the multipart upload recorded in the journal.
+// If the upload of the same file was interrupted, the upload is continued by
+// reusing the parts listed by ListParts having the ETags recorded in the journal.
+// The multipart upload is not aborted on failure to be continued by the next sync.
+func (m *Manager) uploadMultipart(ctx context.Context, file *fileInfo, body io.ReaderAt, input *s3.PutObjectInput, j *syncJournal, tracker *progressTracker) error {
+	u := manager.NewUploader(m.s3, m.uploaderOpts...)
+	partSize := m.uploadPartSize(file.size)
+
+	uploadID, parts, err := m.resumeUpload(ctx, file, input, partSize, j)
+	if err != nil {
+		return err
+	}
+	if uploadID == "" {
+		createInput := &s3.CreateMultipartUploadInput{
+			Bucket:             input.Bucket,
+			Key:                input.Key,
+			ACL:                input.ACL,
+			ContentType:        input.ContentType,
+			CacheControl:       input.CacheControl,
+			ContentDisposition: input.ContentDisposition,
+			ContentEncoding:    input.ContentEncoding,
+			ContentLanguage:    input.ContentLanguage,
+			Expires:            input.Expires,
+			Metadata:           input.Metadata,
+			StorageClass:       input.StorageClass,
+			Tagging:            input.Tagging,
+		}
+		m.sse.createMultipartUpload(createInput)
+		upload, err := m.s3.CreateMultipartUpload(ctx, createInput)
+		if err != nil {
+			return err
+		}
+		uploadID = aws.ToString(upload.UploadId)
+		j.append(journalEntry{
+			Type:     journalUpload,
+			Name:     filepath.ToSlash(file.name),
+			Size:     file.size,
+			Mtime:    file.lastModified.UnixNano(),
+			UploadID: uploadID,
+			Bucket:   aws.ToString(input.Bucket),
+			Key:      aws.ToString(input.Key),
+			PartSize: partSize,
+		})
+	}
+
+	nParts := int((file.size + partSize - 1) / partSize)
+	completed := make([]types.CompletedPart, nParts)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := u.Concurrency
+	if jobs < 1 {
+		jobs = manager.DefaultUploadConcurrency
+	}
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	errs := &multiErr{}
+
+	for i := 0; i < nParts; i++ {
+		start := int64(i) * partSize
+		size := min(partSize, file.size-start)
+		if p, ok := parts[int32(i+1)]; ok {
+			completed[i] = p
+			tracker.add(start, int(size))
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			in := &s3.UploadPartInput{
+				Bucket:        input.Bucket,
+				Key:           input.Key,
+				UploadId:      &uploadID,
+				PartNumber:    aws.Int32(int32(i + 1)),
+				Body:          io.NewSectionReader(body, start, size),
+				ContentLength: aws.Int64(size),
+			}
+			m.sse.uploadPart(in)
+			out, err := m.s3.UploadPart(ctx, in)
+			if err != nil {
+				errs.Append(err)
+				cancel()
+				return
+			}
+			completed[i] = types.CompletedPart{
+				ETag:           out.ETag,
+				PartNumber:     in.PartNumber,
+				ChecksumCRC32:  out.ChecksumCRC32,
+				ChecksumCRC32C: out.ChecksumCRC32C,
+				ChecksumSHA1:   out.ChecksumSHA1,
+				ChecksumSHA256: out.ChecksumSHA256,
+			}
+			j.append(journalEntry{
+				Type:       journalPart,
+				Name:       filepath.ToSlash(file.name),
+				UploadID:   uploadID,
+				PartNumber: *in.PartNumber,
+				ETag:       aws.ToString(out.ETag),
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	if err := errs.ErrOrNil(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := m.s3.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          input.Bucket,
+		Key:             input.Key,
+		UploadId:        &uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	}); err != nil {
+		return err
+	}
+	j.end(filepath.ToSlash(file.name), uploadID)
+	return nil
+}
+
+// resumeUpload returns the ID of the multipart upload of the file recorded in
+// the journal and its uploaded parts.
+// Empty ID is returned if the upload can't be continued, e.g. the file is modified.
+func (m *Manager) resumeUpload(ctx context.Context, file *fileInfo, input *s3.PutObjectInput, partSize int64, j *syncJournal) (string, map[int32]types.CompletedPart, error) {
+	u := j.upload(file)
+	if u == nil {
+		return "", nil, nil
+	}
+	if u.Size != file.size || u.Mtime != file.lastModified.UnixNano() || u.PartSize != partSize {
+		// Clean up the parts of the stale upload.
+		_, err := m.s3.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   input.Bucket,
+			Key:      input.Key,
+			UploadId: &u.UploadID,
+		})
+		var noSuchUpload *types.NoSuchUpload
+		if err != nil && !errors.As(err, &noSuchUpload) {
+			return "", nil, err
+		}
+		j.end(u.Name, u.UploadID)
+		return "", nil, nil
+	}
+
+	parts := make(map[int32]types.CompletedPart)
+	listInput := &s3.ListPartsInput{
+		Bucket:   input.Bucket,
+		Key:      input.Key,
+		UploadId: &u.UploadID,
+	}
+	m.sse.listParts(listInput)
+	for {
+		out, err := m.s3.ListParts(ctx, listInput)
+		var noSuchUpload *types.NoSuchUpload
+		if errors.As(err, &noSuchUpload) {
+			// The upload is completed or aborted.
+			j.end(u.Name, u.UploadID)
+			return "", nil, nil
+		} else if err != nil {
+			return "", nil, err
+		}
+		for _, p := range out.Parts {
+			n := aws.ToInt32(p.PartNumber)
+			start := int64(n-1) * partSize
+			if etag, ok := u.parts[n]; !ok || etag != aws.ToString(p.ETag) ||
+				aws.ToInt64(p.Size) != min(partSize, file.size-start) {
+				continue
+			}
+			parts[n] = types.CompletedPart{
+				ETag:           p.ETag,
+				PartNumber:     p.PartNumber,
+				ChecksumCRC32:  p.ChecksumCRC32,
+				ChecksumCRC32C: p.ChecksumCRC32C,
+				ChecksumSHA1:   p.ChecksumSHA1,
+				ChecksumSHA256: p.ChecksumSHA256,
+			}
+		}
+		if !aws.ToBool(out.IsTruncated) {
+			break
+		}
+		listInput.PartNumberMarker = out.NextPartNumberMarker
+	}
+	return u.UploadID, parts, nil
+}
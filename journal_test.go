@@ -0,0 +1,183 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/seqsense/s3sync/v2/fakes3"
+)
+
+// interruptedClient fails UploadPart of the given part number.
+type interruptedClient struct {
+	*fakes3.Client
+	mu        sync.Mutex
+	failPart  int32
+	parts     []int32
+	uploadIDs []string
+}
+
+func (c *interruptedClient) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	out, err := c.Client.CreateMultipartUpload(ctx, params, optFns...)
+	if err == nil {
+		c.mu.Lock()
+		c.uploadIDs = append(c.uploadIDs, aws.ToString(out.UploadId))
+		c.mu.Unlock()
+	}
+	return out, err
+}
+
+func (c *interruptedClient) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	c.mu.Lock()
+	c.parts = append(c.parts, aws.ToInt32(params.PartNumber))
+	fail := aws.ToInt32(params.PartNumber) == c.failPart
+	c.mu.Unlock()
+	if fail {
+		return nil, errors.New("interrupted")
+	}
+	return c.Client.UploadPart(ctx, params, optFns...)
+}
+
+func TestJournal(t *testing.T) {
+	source := t.TempDir()
+	journal := filepath.Join(t.TempDir(), "journal", "sync.jsonl")
+	mtime := time.Now().Add(-time.Hour)
+	large := bytes.Repeat([]byte("0123456789abcdef"), int(2*manager.MinUploadPartSize+1024)/16)
+	for name, data := range map[string][]byte{"a": []byte("a"), "large": large} {
+		filename := filepath.Join(source, name)
+		if err := os.WriteFile(filename, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(filename, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := &interruptedClient{Client: fakes3.New("bucket"), failPart: 3}
+	opts := []Option{
+		WithJournal(journal),
+		WithParallel(1),
+		WithUploaderOptions(func(u *manager.Uploader) { u.Concurrency = 1 }),
+	}
+
+	if err := NewWithClient(c, opts...).Sync(context.Background(), source, "s3://bucket/dir"); err == nil {
+		t.Fatal("Sync should fail")
+	}
+	if _, err := os.Stat(journal); err != nil {
+		t.Fatalf("Journal should be kept: %v", err)
+	}
+	// The journal of another sync is not overwritten.
+	kept, err := os.ReadFile(journal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewWithClient(c, opts...).Sync(context.Background(), t.TempDir(), "s3://bucket/other"); err == nil {
+		t.Fatal("Sync of another pair should fail")
+	}
+	if data, err := os.ReadFile(journal); err != nil || !bytes.Equal(kept, data) {
+		t.Fatalf("Journal should be kept: %v", err)
+	}
+	// The partially written entry is discarded.
+	f, err := os.OpenFile(journal, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"type":"do`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	// The completed file is skipped even if the dest is modified.
+	putFakeObject(t, c.Client, "bucket", "dir/a", []byte("modified"))
+
+	c.failPart, c.parts = 0, nil
+	m := NewWithClient(c, opts...)
+	if err := m.Sync(context.Background(), source, "s3://bucket/dir"); err != nil {
+		t.Fatal("Sync should be successful", err)
+	}
+	if len(c.parts) != 1 || c.parts[0] != 3 {
+		t.Errorf("Only the failed part is expected to be uploaded, got %v", c.parts)
+	}
+	if n := m.GetStatistics().Files; n != 1 {
+		t.Errorf("Expected 1 file to be synced, got %d", n)
+	}
+	out, err := c.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("dir/large"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(large, data) {
+		t.Error("Uploaded object differs from the file")
+	}
+	if _, err := os.Stat(journal); !os.IsNotExist(err) {
+		t.Errorf("Journal should be removed on completion: %v", err)
+	}
+}
+
+func TestJournalAbort(t *testing.T) {
+	source := t.TempDir()
+	journal := filepath.Join(t.TempDir(), "sync.jsonl")
+	large := bytes.Repeat([]byte("0123456789abcdef"), int(2*manager.MinUploadPartSize+1024)/16)
+	if err := os.WriteFile(filepath.Join(source, "large"), large, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &interruptedClient{Client: fakes3.New("bucket"), failPart: 3}
+	opts := []Option{
+		WithJournal(journal),
+		WithUploaderOptions(func(u *manager.Uploader) { u.Concurrency = 1 }),
+	}
+	if err := NewWithClient(c, opts...).Sync(context.Background(), source, "s3://bucket/dir"); err == nil {
+		t.Fatal("Sync should fail")
+	}
+	if len(c.uploadIDs) != 1 {
+		t.Fatalf("Expected 1 multipart upload, got %v", c.uploadIDs)
+	}
+
+	// The upload of the file removed after the interruption is aborted on completion.
+	if err := os.Remove(filepath.Join(source, "large")); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewWithClient(c, opts...).Sync(context.Background(), source, "s3://bucket/dir"); err != nil {
+		t.Fatal("Sync should be successful", err)
+	}
+	_, err := c.ListParts(context.Background(), &s3.ListPartsInput{
+		Bucket:   aws.String("bucket"),
+		Key:      aws.String("dir/large"),
+		UploadId: aws.String(c.uploadIDs[0]),
+	})
+	var noSuchUpload *types.NoSuchUpload
+	if !errors.As(err, &noSuchUpload) {
+		t.Errorf("Multipart upload should be aborted, got %v", err)
+	}
+	if _, err := os.Stat(journal); !os.IsNotExist(err) {
+		t.Errorf("Journal should be removed on completion: %v", err)
+	}
+}
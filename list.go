@@ -0,0 +1,115 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// keyRange is the range of the S3 keys after startAfter up to end inclusive.
+// Empty startAfter and end mean that the range is unbounded.
+type keyRange struct {
+	startAfter, end string
+}
+
+// listS3FilesParallel lists the s3 files by splitting the keys into the ranges
+// and listing them by the given number of the concurrent requests.
+// The files are sent to the channel in order of the keys as the sequential listing.
+func (m *Manager) listS3FilesParallel(ctx context.Context, c chan *fileInfo, path *s3Path, jobs int) {
+	ranges, err := m.listRanges(ctx, path)
+	if err != nil {
+		sendErrorInfoToChannel(ctx, c, &ListError{Path: path.String(), Err: err})
+		return
+	}
+
+	// Capacity of the queue limits the number of the ranges listed ahead of
+	// the one being sent.
+	shards := make(chan chan *fileInfo, jobs-1)
+	go func() {
+		defer close(shards)
+		for _, r := range ranges {
+			shard := make(chan *fileInfo, listBufferSize)
+			select {
+			case shards <- shard:
+			case <-ctx.Done():
+				return
+			}
+			go func() {
+				defer close(shard)
+				m.listS3Range(ctx, shard, path, r)
+			}()
+		}
+	}()
+
+	for shard := range shards {
+		for fi := range shard {
+			select {
+			case c <- fi:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// listRanges splits the keys under the path into the ranges by the split points
+// specified by WithListSplitPoints, or by the sub-directories of the path.
+func (m *Manager) listRanges(ctx context.Context, path *s3Path) ([]keyRange, error) {
+	dir := path.bucketPrefix
+	if dir != "" && !strings.HasSuffix(dir, "/") {
+		dir += "/"
+	}
+
+	var points []string
+	if len(m.listSplitPoints) > 0 {
+		for _, p := range m.listSplitPoints {
+			points = append(points, dir+p)
+		}
+		sort.Strings(points)
+	} else {
+		var token *string
+		for {
+			list, err := m.s3.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+				Bucket:            &path.bucket,
+				Prefix:            &dir,
+				Delimiter:         aws.String("/"),
+				ContinuationToken: token,
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range list.CommonPrefixes {
+				points = append(points, aws.ToString(p.Prefix))
+			}
+			if token = list.NextContinuationToken; token == nil {
+				break
+			}
+		}
+	}
+
+	ranges := make([]keyRange, 0, len(points)+1)
+	var start string
+	for _, p := range points {
+		if p == start {
+			continue
+		}
+		ranges = append(ranges, keyRange{startAfter: start, end: p})
+		start = p
+	}
+	return append(ranges, keyRange{startAfter: start}), nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/seqsense/s3sync/v2/fakes3"
+)
+
+// listCounter counts the concurrent and the total ListObjectsV2 requests.
+type listCounter struct {
+	*fakes3.Client
+	mu            sync.Mutex
+	n, max, total int
+}
+
+func (c *listCounter) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	c.mu.Lock()
+	c.n++
+	c.total++
+	if c.n > c.max {
+		c.max = c.n
+	}
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.n--
+		c.mu.Unlock()
+	}()
+	time.Sleep(10 * time.Millisecond)
+	return c.Client.ListObjectsV2(ctx, params, optFns...)
+}
+
+func TestListS3FilesParallel(t *testing.T) {
+	c := &listCounter{Client: fakes3.New("bucket")}
+	keys := []string{"dir", "dir-x/a", "dir.x", "dir/", "dir/a", "dir/a-b", "dir/b/", "dir/z"}
+	for _, d := range []string{"a", "b", "c", "d"} {
+		for i := 0; i < fakes3.DefaultMaxKeys+10; i++ {
+			keys = append(keys, fmt.Sprintf("dir/%s/%04d", d, i))
+		}
+	}
+	for _, key := range keys {
+		putFakeObject(t, c.Client, "bucket", key, nil)
+	}
+
+	list := func(t *testing.T, m *Manager, rawURL string) []string {
+		t.Helper()
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path, err := urlToS3Path(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for fi := range m.listS3Files(context.Background(), path) {
+			if fi.err != nil {
+				t.Fatal(fi.err)
+			}
+			names = append(names, fi.name)
+		}
+		return names
+	}
+
+	for _, u := range []string{"s3://bucket", "s3://bucket/dir", "s3://bucket/dir/", "s3://bucket/dir/a"} {
+		t.Run(u, func(t *testing.T) {
+			expected := list(t, NewWithClient(c), u)
+			testCases := map[string][]Option{
+				"SubDirectories": {WithListConcurrency(3)},
+				"SplitPoints":    {WithListConcurrency(3), WithListSplitPoints("c/0500", "a/", "b/0100", "a/")},
+			}
+			for name, opts := range testCases {
+				t.Run(name, func(t *testing.T) {
+					names := list(t, NewWithClient(c, opts...), u)
+					if !reflect.DeepEqual(expected, names) {
+						t.Errorf("Expected %d files same as the sequential listing, got %d files", len(expected), len(names))
+					}
+				})
+			}
+		})
+	}
+
+	t.Run("Concurrency", func(t *testing.T) {
+		c.max = 0
+		list(t, NewWithClient(c, WithListConcurrency(3)), "s3://bucket/dir")
+		if c.max != 3 {
+			t.Errorf("Expected 3 concurrent requests, got %d", c.max)
+		}
+	})
+}
@@ -0,0 +1,124 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// copyLocalBufferSize is the size of the buffer to copy the local files.
+const copyLocalBufferSize = 1024 * 1024
+
+// copyLocal copies the local file to the target.
+func (m *Manager) copyLocal(ctx context.Context, file *fileInfo, sourcePath, destPath string, progress func(int64)) error {
+	if file.symlink {
+		return m.copySymlink(file, localSource(file, sourcePath), localTarget(file, destPath), progress)
+	}
+	reader, err := os.Open(localSource(file, sourcePath))
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	targetFilename := localTarget(file, destPath)
+	written, err := writeLocalFile(ctx, reader, targetFilename, file.lastModified, newProgressTracker(0, progress))
+	if err != nil {
+		return err
+	}
+	if err := m.restoreFileAttributes(targetFilename, file); err != nil {
+		return err
+	}
+	m.updateFileTransferStatistics(written)
+	return nil
+}
+
+// writeLocalFile writes the contents to a temporary file and renames it to the target,
+// so that the target is never left truncated.
+// The modification time of the target is set to modTime.
+func writeLocalFile(ctx context.Context, r io.Reader, targetFilename string, modTime time.Time, tracker *progressTracker) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(targetFilename), 0755); err != nil {
+		return 0, err
+	}
+
+	writer, err := createTempFile(targetFilename)
+	if err != nil {
+		return 0, err
+	}
+	defer releaseTempFile(writer.Name())
+	written, err := copyToFile(ctx, r, writer, tracker)
+	if err == nil {
+		err = os.Chtimes(writer.Name(), modTime, modTime)
+	}
+	if err == nil {
+		err = os.Rename(writer.Name(), targetFilename)
+	}
+	if err != nil {
+		os.Remove(writer.Name())
+		return written, err
+	}
+	return written, nil
+}
+
+// copyToFile copies the contents to the file, syncs it to the disk and closes it.
+// It returns the number of bytes copied.
+func copyToFile(ctx context.Context, r io.Reader, writer *os.File, tracker *progressTracker) (int64, error) {
+	defer writer.Close()
+
+	buf := make([]byte, copyLocalBufferSize)
+	var written int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		n, err := r.Read(buf)
+		if n > 0 {
+			if _, err := writer.Write(buf[:n]); err != nil {
+				return written, err
+			}
+			tracker.add(written, n)
+			written += int64(n)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+	if err := writer.Sync(); err != nil {
+		return written, err
+	}
+	return written, writer.Close()
+}
+
+// sortFileInfos sorts the files in a directory in the order of the S3 keys,
+// where the names of the directories are followed by "/", so that walking
+// the directories recursively lists the files in lexicographical order of the
+// slash separated paths.
+func sortFileInfos(stats []os.FileInfo) {
+	sort.Slice(stats, func(i, j int) bool {
+		return sortKey(stats[i].Name(), stats[i].IsDir()) < sortKey(stats[j].Name(), stats[j].IsDir())
+	})
+}
+
+func sortKey(name string, dir bool) string {
+	if dir {
+		return name + "/"
+	}
+	return name
+}
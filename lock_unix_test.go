@@ -0,0 +1,51 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build unix
+
+package s3sync
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/seqsense/s3sync/v2/fakes3"
+)
+
+func TestJournalLock(t *testing.T) {
+	m := NewWithClient(fakes3.New("bucket"), WithJournal(filepath.Join(t.TempDir(), "sync.jsonl")))
+	pair, err := m.parseSyncPair(t.TempDir(), "s3://bucket/dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := m.openJournal(pair)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.openJournal(pair); err == nil {
+		t.Error("Journal used by another sync should not be opened")
+	}
+
+	// The lock is released when the sync is interrupted.
+	if err := j.finish(context.Background(), m.s3, false); err != nil {
+		t.Fatal(err)
+	}
+	j, err = m.openJournal(pair)
+	if err != nil {
+		t.Fatal("Journal should be opened after released", err)
+	}
+	if err := j.finish(context.Background(), m.s3, true); err != nil {
+		t.Fatal(err)
+	}
+}
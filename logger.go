@@ -12,7 +12,14 @@
 // limitations under the License.
 package s3sync
 
-import "log"
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"path"
+	"time"
+)
 
 // LoggerIF is the logger interface which this library requires.
 type LoggerIF interface {
@@ -21,18 +28,134 @@ type LoggerIF interface {
 	Logf(format string, v ...any)
 }
 
+// EventLogger is the optional interface of LoggerIF to receive the structured log events.
+// If the logger implements EventLogger, LogEvent is called instead of Logf.
+type EventLogger interface {
+	LogEvent(e LogEvent)
+}
+
+// LogEvent is the structured log event of a file operation.
+//
+// The start of each operation is logged at debug level (info level in dry-run mode),
+// and the completion and the failure are logged at info and error level respectively.
+// Loggers not implementing EventLogger only receive the messages of the start.
+type LogEvent struct {
+	Level     slog.Level
+	Message   string
+	Operation OperationType
+	// Bucket and Key are the S3 object to be operated.
+	// It is the destination object on OperationCopy between S3 locations.
+	Bucket string
+	Key    string
+	// Path is the local file path to be operated.
+	// It is the destination file on OperationCopy between local paths.
+	Path string
+	Size int64
+	// Duration is the time taken by the operation. It is set on the completion and the failure.
+	Duration time.Duration
+	Err      error
+}
+
 // Logger is the logger instance.
 var logger LoggerIF
 
 // SetLogger sets the logger.
+// It is used by the Managers created without WithLogger.
 func SetLogger(l LoggerIF) {
 	logger = l
 }
 
-func logf(format string, v ...any) {
-	if logger == nil {
-		log.Printf(format, v...)
+// log sends the event to the logger of the Manager.
+// If legacy is false, the event is dropped unless the logger implements EventLogger.
+func (m *Manager) log(e LogEvent, legacy bool) {
+	l := m.logger
+	if l == nil {
+		l = logger
+	}
+	if el, ok := l.(EventLogger); ok {
+		el.LogEvent(e)
+		return
+	}
+	if !legacy {
+		return
+	}
+	if l == nil {
+		log.Print(e.Message)
 		return
 	}
-	logger.Logf(format, v...)
+	l.Logf("%s", e.Message)
+}
+
+// logEvent returns the log event of the start of the operation.
+func (p *syncPair) logEvent(op *fileOp) LogEvent {
+	e := LogEvent{
+		Level: slog.LevelDebug,
+		Size:  op.size,
+	}
+	switch {
+	case op.op == opDelete && p.destBackend != nil:
+		e.Operation = OperationDelete
+		e.Message = "delete: " + joinName(p.dest, op.name)
+	case p.destBackend != nil:
+		e.Operation = OperationCopy
+		e.Message = fmt.Sprintf("copy: %s to %s", joinName(p.source, op.name), joinName(p.dest, op.name))
+	case op.op == opDelete && p.destS3 != nil:
+		dest := remoteTarget(op.fileInfo, p.destS3)
+		e.Operation, e.Bucket, e.Key = OperationDelete, dest.bucket, dest.bucketPrefix
+		e.Message = "delete: " + dest.String()
+	case op.op == opDelete:
+		e.Operation, e.Path = OperationDelete, localTarget(op.fileInfo, p.dest)
+		e.Message = "delete: " + e.Path
+	case p.sourceS3 != nil && p.destS3 != nil:
+		e.Operation, e.Bucket, e.Key = OperationCopy, p.destS3.bucket, path.Join(p.destS3.bucketPrefix, op.name)
+		e.Message = fmt.Sprintf("copy: %s to %s", p.sourceS3.joinedURL(op.name), p.destS3.joinedURL(op.name))
+	case p.sourceS3 == nil && p.destS3 == nil:
+		e.Operation, e.Path = OperationCopy, localTarget(op.fileInfo, p.dest)
+		e.Message = fmt.Sprintf("copy: %s to %s", localSource(op.fileInfo, p.source), e.Path)
+	case p.sourceS3 != nil:
+		e.Operation, e.Bucket, e.Key = OperationDownload, p.sourceS3.bucket, op.objectKey()
+		e.Path = localTarget(op.fileInfo, p.dest)
+		e.Message = fmt.Sprintf("download: %s to %s", p.sourceS3.joinedURL(op.name), e.Path)
+	default:
+		dest := remoteTarget(op.fileInfo, p.destS3)
+		e.Operation, e.Bucket, e.Key = OperationUpload, dest.bucket, dest.bucketPrefix
+		e.Path = localSource(op.fileInfo, p.source)
+		e.Message = fmt.Sprintf("upload: %s to %s", op.name, dest.String())
+	}
+	return e
+}
+
+// slogLogger is the LoggerIF backed by slog.Logger.
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlogLogger returns the logger which writes the structured log events to the slog.Logger.
+// The messages passed to Logf are logged at info level.
+func NewSlogLogger(l *slog.Logger) LoggerIF {
+	return &slogLogger{l: l}
+}
+
+func (s *slogLogger) Logf(format string, v ...any) {
+	s.l.Info(fmt.Sprintf(format, v...))
+}
+
+func (s *slogLogger) LogEvent(e LogEvent) {
+	attrs := []slog.Attr{
+		slog.String("operation", string(e.Operation)),
+		slog.Int64("size", e.Size),
+	}
+	if e.Bucket != "" {
+		attrs = append(attrs, slog.String("bucket", e.Bucket), slog.String("key", e.Key))
+	}
+	if e.Path != "" {
+		attrs = append(attrs, slog.String("path", e.Path))
+	}
+	if e.Duration != 0 {
+		attrs = append(attrs, slog.Duration("duration", e.Duration))
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.Any("error", e.Err))
+	}
+	s.l.LogAttrs(context.Background(), e.Level, e.Message, attrs...)
 }
@@ -0,0 +1,186 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type eventLogger struct {
+	dummyLogger
+	events []LogEvent
+}
+
+func (l *eventLogger) LogEvent(e LogEvent) {
+	l.events = append(l.events, e)
+}
+
+func TestManagerLog(t *testing.T) {
+	start := LogEvent{Level: slog.LevelDebug, Message: "upload: foo to s3://bucket/foo"}
+	done := LogEvent{Level: slog.LevelInfo, Message: "upload completed"}
+
+	t.Run("Legacy", func(t *testing.T) {
+		var logs []string
+		m := New(getSession(), WithLogger(createLoggerWithLogFunc(func(format string, v ...any) {
+			logs = append(logs, fmt.Sprintf(format, v...))
+		})))
+		m.log(start, true)
+		m.log(done, false)
+		if !reflect.DeepEqual([]string{start.Message}, logs) {
+			t.Errorf("Only the legacy message is expected to be logged, got %v", logs)
+		}
+	})
+	t.Run("EventLogger", func(t *testing.T) {
+		l := &eventLogger{dummyLogger: dummyLogger{logf: func(string, ...any) {
+			t.Error("Logf must not be called")
+		}}}
+		m := New(getSession(), WithLogger(l))
+		m.log(start, true)
+		m.log(done, false)
+		if !reflect.DeepEqual([]LogEvent{start, done}, l.events) {
+			t.Errorf("Unexpected events: %v", l.events)
+		}
+	})
+	t.Run("PerManager", func(t *testing.T) {
+		var global, local int
+		SetLogger(createLoggerWithLogFunc(func(string, ...any) { global++ }))
+		defer SetLogger(nil)
+
+		New(getSession()).log(start, true)
+		New(getSession(), WithLogger(createLoggerWithLogFunc(func(string, ...any) { local++ }))).log(start, true)
+		if global != 1 || local != 1 {
+			t.Errorf("Each logger is expected to be called once, global: %d, local: %d", global, local)
+		}
+	})
+}
+
+func TestSyncPairLogEvent(t *testing.T) {
+	s3Pair := &syncPair{
+		source:   "s3://source/prefix",
+		dest:     "s3://dest/prefix2",
+		sourceS3: &s3Path{bucket: "source", bucketPrefix: "prefix"},
+		destS3:   &s3Path{bucket: "dest", bucketPrefix: "prefix2"},
+	}
+	downloadPair := &syncPair{
+		source:   "s3://source/prefix",
+		dest:     "/tmp/dest",
+		sourceS3: &s3Path{bucket: "source", bucketPrefix: "prefix"},
+	}
+	uploadPair := &syncPair{
+		source: "/tmp/source",
+		dest:   "s3://dest/prefix2",
+		destS3: &s3Path{bucket: "dest", bucketPrefix: "prefix2"},
+	}
+	remoteFile := &fileInfo{name: "foo/bar", path: "prefix/foo/bar", size: 10}
+	localFile := &fileInfo{name: "foo/bar", path: "/tmp/source/foo/bar", size: 10}
+
+	testCases := map[string]struct {
+		pair     *syncPair
+		op       *fileOp
+		expected LogEvent
+	}{
+		"Copy": {
+			pair: s3Pair,
+			op:   &fileOp{fileInfo: remoteFile},
+			expected: LogEvent{
+				Level: slog.LevelDebug, Message: "copy: s3://source/prefix/foo/bar to s3://dest/prefix2/foo/bar",
+				Operation: OperationCopy, Bucket: "dest", Key: "prefix2/foo/bar", Size: 10,
+			},
+		},
+		"Download": {
+			pair: downloadPair,
+			op:   &fileOp{fileInfo: remoteFile},
+			expected: LogEvent{
+				Level: slog.LevelDebug, Message: "download: s3://source/prefix/foo/bar to /tmp/dest/foo/bar",
+				Operation: OperationDownload, Bucket: "source", Key: "prefix/foo/bar", Path: "/tmp/dest/foo/bar", Size: 10,
+			},
+		},
+		"Upload": {
+			pair: uploadPair,
+			op:   &fileOp{fileInfo: localFile},
+			expected: LogEvent{
+				Level: slog.LevelDebug, Message: "upload: foo/bar to s3://dest/prefix2/foo/bar",
+				Operation: OperationUpload, Bucket: "dest", Key: "prefix2/foo/bar", Path: "/tmp/source/foo/bar", Size: 10,
+			},
+		},
+		"DeleteRemote": {
+			pair: s3Pair,
+			op:   &fileOp{fileInfo: remoteFile, op: opDelete},
+			expected: LogEvent{
+				Level: slog.LevelDebug, Message: "delete: s3://dest/prefix2/foo/bar",
+				Operation: OperationDelete, Bucket: "dest", Key: "prefix2/foo/bar", Size: 10,
+			},
+		},
+		"DeleteLocal": {
+			pair: downloadPair,
+			op:   &fileOp{fileInfo: remoteFile, op: opDelete},
+			expected: LogEvent{
+				Level: slog.LevelDebug, Message: "delete: /tmp/dest/foo/bar",
+				Operation: OperationDelete, Path: "/tmp/dest/foo/bar", Size: 10,
+			},
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			if e := tt.pair.logEvent(tt.op); !reflect.DeepEqual(tt.expected, e) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, e)
+			}
+		})
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewSlogLogger(slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})))
+
+	l.Logf("hello %s", "world")
+	l.(EventLogger).LogEvent(LogEvent{
+		Level:     slog.LevelError,
+		Message:   "upload failed",
+		Operation: OperationUpload,
+		Bucket:    "bucket",
+		Key:       "foo",
+		Path:      "/tmp/foo",
+		Size:      10,
+		Duration:  time.Second,
+		Err:       errors.New("test"),
+	})
+	l.(EventLogger).LogEvent(LogEvent{
+		Level:     slog.LevelDebug,
+		Message:   "ignored",
+		Operation: OperationDelete,
+	})
+
+	expected := []string{
+		`level=INFO msg="hello world"`,
+		`level=ERROR msg="upload failed" operation=upload size=10 bucket=bucket key=foo path=/tmp/foo duration=1s error=test`,
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); !reflect.DeepEqual(expected, lines) {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(lines, "\n"))
+	}
+}
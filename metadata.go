@@ -0,0 +1,200 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"context"
+	"maps"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ObjectAttributes is the attributes set to the uploaded objects.
+// Nil and empty fields are not set.
+type ObjectAttributes struct {
+	CacheControl       *string
+	ContentDisposition *string
+	ContentEncoding    *string
+	ContentLanguage    *string
+	Expires            *time.Time
+	// Metadata is the user-defined metadata stored with "x-amz-meta-" prefix.
+	Metadata     map[string]string
+	StorageClass types.StorageClass
+	Tags         map[string]string
+}
+
+// AttributesFunc modifies the attributes of the object uploaded from the file.
+// name is the slash separated path of the file relative to the synced directory,
+// and attrs is initialized by the attributes set by the options.
+type AttributesFunc func(name string, attrs *ObjectAttributes)
+
+// objectAttributes returns the attributes of the object uploaded from the file.
+func (m *Manager) objectAttributes(name string) *ObjectAttributes {
+	attrs := m.attrs
+	attrs.Metadata = maps.Clone(m.attrs.Metadata)
+	attrs.Tags = maps.Clone(m.attrs.Tags)
+	for _, fn := range m.attrFuncs {
+		fn(name, &attrs)
+	}
+	return &attrs
+}
+
+// tagging returns the URL query encoded tags.
+func (a *ObjectAttributes) tagging() *string {
+	if len(a.Tags) == 0 {
+		return nil
+	}
+	q := make(url.Values, len(a.Tags))
+	for k, v := range a.Tags {
+		q.Set(k, v)
+	}
+	// Encode spaces as %20 since "+" is not decoded by S3.
+	s := strings.ReplaceAll(q.Encode(), "+", "%20")
+	return &s
+}
+
+// replaceCopyMetadata sets the attributes to the CopyObject request to replace
+// the metadata and the tags of the source object.
+// Content type and the metadata used by s3sync of the source object are kept.
+// Content type is overwritten if WithContentType is specified.
+func (m *Manager) replaceCopyMetadata(ctx context.Context, sourceBucket, sourceKey string, attrs *ObjectAttributes, input *s3.CopyObjectInput) error {
+	head := &s3.HeadObjectInput{
+		Bucket: &sourceBucket,
+		Key:    &sourceKey,
+	}
+	m.sse.headObject(head)
+	out, err := m.s3.HeadObject(ctx, head)
+	if err != nil {
+		return err
+	}
+	input.ContentType = out.ContentType
+	if m.contentType != nil {
+		input.ContentType = m.contentType
+	}
+	input.MetadataDirective = types.MetadataDirectiveReplace
+	input.CacheControl = attrs.CacheControl
+	input.ContentDisposition = attrs.ContentDisposition
+	input.ContentEncoding = attrs.ContentEncoding
+	input.ContentLanguage = attrs.ContentLanguage
+	input.Expires = attrs.Expires
+	input.Metadata = withInternalMetadata(attrs.Metadata, out.Metadata)
+	input.TaggingDirective = types.TaggingDirectiveReplace
+	input.Tagging = attrs.tagging()
+	return nil
+}
+
+// Metadata keys of the attributes of the source file.
+// They are recorded if the object contents differ from the source file.
+const (
+	metaSize  = "s3sync-size"
+	metaMtime = "s3sync-mtime"
+	metaMode  = "s3sync-mode"
+	metaUID   = "s3sync-uid"
+	metaGID   = "s3sync-gid"
+)
+
+// fileOwner is the owner of the local file.
+type fileOwner struct {
+	uid, gid int
+}
+
+// metaPrefix is the prefix of the metadata keys used by s3sync.
+const metaPrefix = "s3sync-"
+
+// withInternalMetadata returns the metadata merged with the metadata used by
+// s3sync in the source metadata.
+func withInternalMetadata(metadata, source map[string]string) map[string]string {
+	for k, v := range source {
+		if !strings.HasPrefix(k, metaPrefix) {
+			continue
+		}
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata[k] = v
+	}
+	return metadata
+}
+
+// setFileMetadata records the size, the modification time, and the mode and
+// the owner if available, of the source file.
+func setFileMetadata(metadata map[string]string, file *fileInfo) {
+	metadata[metaSize] = strconv.FormatInt(file.size, 10)
+	metadata[metaMtime] = file.lastModified.UTC().Format(time.RFC3339Nano)
+	if file.mode != 0 {
+		metadata[metaMode] = "0" + strconv.FormatUint(uint64(file.mode.Perm()), 8)
+	}
+	if file.owner != nil {
+		metadata[metaUID] = strconv.Itoa(file.owner.uid)
+		metadata[metaGID] = strconv.Itoa(file.owner.gid)
+	}
+}
+
+// applyFileMetadata sets the attributes of the source file recorded in the
+// metadata to the file.
+func applyFileMetadata(file *fileInfo, metadata map[string]string) {
+	file.hasMetadata = true
+	file.symlink = metadata[metaSymlink] != ""
+	if size, err := strconv.ParseInt(metadata[metaSize], 10, 64); err == nil {
+		file.size = size
+	}
+	if mtime, err := time.Parse(time.RFC3339Nano, metadata[metaMtime]); err == nil {
+		file.lastModified = mtime
+	}
+	if mode, err := strconv.ParseUint(metadata[metaMode], 8, 32); err == nil {
+		file.mode = os.FileMode(mode).Perm()
+	}
+	uid, uidErr := strconv.Atoi(metadata[metaUID])
+	gid, gidErr := strconv.Atoi(metadata[metaGID])
+	if uidErr == nil && gidErr == nil {
+		file.owner = &fileOwner{uid: uid, gid: gid}
+	}
+}
+
+// restoreFileAttributes sets the mode and the owner of the source file to
+// the local file if they are preserved.
+func (m *Manager) restoreFileAttributes(filename string, file *fileInfo) error {
+	if m.preserveMode && file.mode != 0 {
+		if err := os.Chmod(filename, file.mode); err != nil {
+			return err
+		}
+	}
+	if m.preserveOwner && file.owner != nil {
+		if err := os.Lchown(filename, file.owner.uid, file.owner.gid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// headFileMetadata gets the metadata of the object and applies it to the file.
+func (m *Manager) headFileMetadata(ctx context.Context, bucket string, file *fileInfo) error {
+	input := &s3.HeadObjectInput{
+		Bucket: &bucket,
+		Key:    aws.String(file.objectKey()),
+	}
+	m.sse.headObject(input)
+	out, err := m.s3.HeadObject(ctx, input)
+	if err != nil {
+		return err
+	}
+	applyFileMetadata(file, out.Metadata)
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestObjectAttributes(t *testing.T) {
+	m := New(aws.Config{},
+		WithCacheControl("max-age=60"),
+		WithMetadata(map[string]string{"foo": "bar"}),
+		WithStorageClass(types.StorageClassStandardIa),
+		WithAttributesFunc(func(name string, attrs *ObjectAttributes) {
+			switch {
+			case name == "index.html":
+				attrs.CacheControl = aws.String("no-cache")
+			case path.Ext(name) == ".js":
+				attrs.CacheControl = aws.String("max-age=31536000")
+				attrs.Metadata["type"] = "script"
+			}
+		}),
+	)
+
+	testCases := map[string]ObjectAttributes{
+		"index.html": {
+			CacheControl: aws.String("no-cache"),
+			Metadata:     map[string]string{"foo": "bar"},
+			StorageClass: types.StorageClassStandardIa,
+		},
+		"js/main.js": {
+			CacheControl: aws.String("max-age=31536000"),
+			Metadata:     map[string]string{"foo": "bar", "type": "script"},
+			StorageClass: types.StorageClassStandardIa,
+		},
+		"image.png": {
+			CacheControl: aws.String("max-age=60"),
+			Metadata:     map[string]string{"foo": "bar"},
+			StorageClass: types.StorageClassStandardIa,
+		},
+	}
+	for name, expected := range testCases {
+		if attrs := m.objectAttributes(name); !reflect.DeepEqual(expected, *attrs) {
+			t.Errorf("%s: expected %+v, got %+v", name, expected, *attrs)
+		}
+	}
+}
+
+func TestObjectAttributesTagging(t *testing.T) {
+	if tagging := (&ObjectAttributes{}).tagging(); tagging != nil {
+		t.Errorf("Expected nil, got %s", *tagging)
+	}
+	attrs := &ObjectAttributes{Tags: map[string]string{"b": "1 2", "a": "x&y"}}
+	if tagging := aws.ToString(attrs.tagging()); tagging != "a=x%26y&b=1%202" {
+		t.Errorf("Unexpected tagging: %s", tagging)
+	}
+}
+
+func TestFileMetadata(t *testing.T) {
+	file := &fileInfo{
+		size:         10,
+		lastModified: time.Date(2020, time.January, 2, 3, 4, 5, 6, time.FixedZone("JST", 9*60*60)),
+		mode:         0640,
+		owner:        &fileOwner{uid: 1000, gid: 100},
+	}
+	metadata := make(map[string]string)
+	setFileMetadata(metadata, file)
+	expected := map[string]string{
+		"s3sync-size":  "10",
+		"s3sync-mtime": "2020-01-01T18:04:05.000000006Z",
+		"s3sync-mode":  "0640",
+		"s3sync-uid":   "1000",
+		"s3sync-gid":   "100",
+	}
+	if !reflect.DeepEqual(expected, metadata) {
+		t.Errorf("Expected %v, got %v", expected, metadata)
+	}
+
+	restored := &fileInfo{size: 20, lastModified: time.Now()}
+	applyFileMetadata(restored, metadata)
+	if restored.size != file.size || !restored.lastModified.Equal(file.lastModified) ||
+		restored.mode != file.mode || !reflect.DeepEqual(file.owner, restored.owner) || !restored.hasMetadata {
+		t.Errorf("Expected %+v, got %+v", file, restored)
+	}
+
+	// Attributes not recorded are kept.
+	t0 := time.Now()
+	restored = &fileInfo{size: 20, lastModified: t0}
+	applyFileMetadata(restored, map[string]string{"foo": "bar"})
+	if restored.size != 20 || !restored.lastModified.Equal(t0) || restored.mode != 0 || restored.owner != nil {
+		t.Errorf("Unexpected file info: %+v", restored)
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// maxCopyPartSize is the maximum part size allowed by UploadPartCopy.
+const maxCopyPartSize = 5 * 1024 * 1024 * 1024
+
+// partSizeForCopy returns the part size to copy the object of the given size.
+func (m *Manager) partSizeForCopy(size int64) int64 {
+	partSize := m.copyPartSize
+	if partSize < manager.MinUploadPartSize {
+		partSize = manager.MinUploadPartSize
+	}
+	if n := int64(manager.MaxUploadParts); (size+partSize-1)/partSize > n {
+		partSize = (size + n - 1) / n
+	}
+	if partSize > maxCopyPartSize {
+		partSize = maxCopyPartSize
+	}
+	return partSize
+}
+
+// copyMultipart copies the S3 object by UploadPartCopy requests.
+// The multipart upload is aborted on failure.
+func (m *Manager) copyMultipart(ctx context.Context, sourceBucket, sourceKey, copySource, destBucket, destKey string, attrs *ObjectAttributes, tracker *progressTracker) error {
+	// Multipart upload doesn't inherit the source object's attributes and tags
+	// unlike CopyObject.
+	headInput := &s3.HeadObjectInput{
+		Bucket: &sourceBucket,
+		Key:    &sourceKey,
+	}
+	m.sse.headObject(headInput)
+	head, err := m.s3.HeadObject(ctx, headInput)
+	if err != nil {
+		return err
+	}
+	size := aws.ToInt64(head.ContentLength)
+
+	input := &s3.CreateMultipartUploadInput{
+		Bucket:             &destBucket,
+		Key:                &destKey,
+		ACL:                m.acl,
+		CacheControl:       head.CacheControl,
+		ContentDisposition: head.ContentDisposition,
+		ContentEncoding:    head.ContentEncoding,
+		ContentLanguage:    head.ContentLanguage,
+		ContentType:        head.ContentType,
+		Expires:            head.Expires,
+		Metadata:           head.Metadata,
+		StorageClass:       attrs.StorageClass,
+	}
+	if input.StorageClass == "" {
+		input.StorageClass = head.StorageClass
+	}
+	if m.replaceMeta {
+		input.CacheControl = attrs.CacheControl
+		input.ContentDisposition = attrs.ContentDisposition
+		input.ContentEncoding = attrs.ContentEncoding
+		input.ContentLanguage = attrs.ContentLanguage
+		input.Expires = attrs.Expires
+		input.Metadata = withInternalMetadata(attrs.Metadata, head.Metadata)
+		input.Tagging = attrs.tagging()
+		if m.contentType != nil {
+			input.ContentType = m.contentType
+		}
+	} else if aws.ToInt32(head.TagCount) > 0 {
+		tagging, err := m.s3.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+			Bucket: &sourceBucket,
+			Key:    &sourceKey,
+		})
+		if err != nil {
+			return err
+		}
+		tags := &ObjectAttributes{Tags: make(map[string]string, len(tagging.TagSet))}
+		for _, tag := range tagging.TagSet {
+			tags.Tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+		input.Tagging = tags.tagging()
+	}
+	m.sse.createMultipartUpload(input)
+	upload, err := m.s3.CreateMultipartUpload(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	partInput := &s3.UploadPartCopyInput{
+		Bucket:            &destBucket,
+		Key:               &destKey,
+		UploadId:          upload.UploadId,
+		CopySource:        &copySource,
+		CopySourceIfMatch: head.ETag,
+	}
+	m.sse.uploadPartCopy(partInput)
+	parts, err := m.copyParts(ctx, size, tracker, partInput)
+	if err == nil {
+		_, err = m.s3.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          &destBucket,
+			Key:             &destKey,
+			UploadId:        upload.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+		})
+	}
+	if err != nil {
+		// Use a context not canceled even if the sync is canceled to clean up the parts.
+		if _, abortErr := m.s3.AbortMultipartUpload(context.WithoutCancel(ctx), &s3.AbortMultipartUploadInput{
+			Bucket:   &destBucket,
+			Key:      &destKey,
+			UploadId: upload.UploadId,
+		}); abortErr != nil {
+			return fmt.Errorf("%w (failed to abort multipart upload: %v)", err, abortErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// copyParts copies the parts in parallel and returns the list of the completed parts.
+// params is used as a template of the UploadPartCopy requests.
+func (m *Manager) copyParts(ctx context.Context, size int64, tracker *progressTracker, params *s3.UploadPartCopyInput) ([]types.CompletedPart, error) {
+	partSize := m.partSizeForCopy(size)
+	nParts := int((size + partSize - 1) / partSize)
+	parts := make([]types.CompletedPart, nParts)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := m.copyJobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	errs := &multiErr{}
+
+	for i := 0; i < nParts; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			start := int64(i) * partSize
+			end := start + partSize - 1
+			if end >= size {
+				end = size - 1
+			}
+			in := *params
+			in.PartNumber = aws.Int32(int32(i + 1))
+			in.CopySourceRange = aws.String(fmt.Sprintf("bytes=%d-%d", start, end))
+			out, err := m.s3.UploadPartCopy(ctx, &in)
+			if err != nil {
+				errs.Append(err)
+				cancel()
+				return
+			}
+			parts[i] = types.CompletedPart{
+				ETag:           out.CopyPartResult.ETag,
+				PartNumber:     in.PartNumber,
+				ChecksumCRC32:  out.CopyPartResult.ChecksumCRC32,
+				ChecksumCRC32C: out.CopyPartResult.ChecksumCRC32C,
+				ChecksumSHA1:   out.CopyPartResult.ChecksumSHA1,
+				ChecksumSHA256: out.CopyPartResult.ChecksumSHA256,
+			}
+			tracker.add(start, int(end-start+1))
+		}(i)
+	}
+	wg.Wait()
+
+	if err := errs.ErrOrNil(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return parts, nil
+}
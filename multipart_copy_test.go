@@ -0,0 +1,60 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"testing"
+)
+
+func TestPartSizeForCopy(t *testing.T) {
+	const (
+		MiB = 1024 * 1024
+		GiB = 1024 * MiB
+		TiB = 1024 * GiB
+	)
+	testCases := map[string]struct {
+		partSize int64
+		size     int64
+		expected int64
+	}{
+		"Default": {
+			partSize: DefaultCopyPartSize,
+			size:     6 * GiB,
+			expected: DefaultCopyPartSize,
+		},
+		"TooSmall": {
+			partSize: 1,
+			size:     6 * GiB,
+			expected: 5 * MiB,
+		},
+		"TooManyParts": {
+			partSize: 5 * MiB,
+			size:     100 * GiB,
+			expected: (100*GiB + 9999) / 10000,
+		},
+		"TooLarge": {
+			partSize: 6 * GiB,
+			size:     4 * TiB,
+			expected: 5 * GiB,
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			m := New(getSession(), WithCopyPartSize(tt.partSize))
+			if partSize := m.partSizeForCopy(tt.size); partSize != tt.expected {
+				t.Errorf("Expected part size %d, got %d", tt.expected, partSize)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// Observer receives the events of the sync operation of each file.
+// The methods are called concurrently from the parallel sync jobs.
+type Observer interface {
+	// OnPlanned is called when the file is selected to be synced.
+	OnPlanned(op Operation)
+	// OnStart is called before the operation is started.
+	OnStart(op Operation)
+	// OnProgress is called with the total number of bytes transferred so far.
+	OnProgress(op Operation, bytesDone int64)
+	// OnComplete is called when the operation is successfully completed.
+	OnComplete(op Operation)
+	// OnSkip is called for the file which is up to date on the destination.
+	// Reason of the operation is empty.
+	OnSkip(op Operation)
+	// OnError is called when the operation is failed.
+	OnError(op Operation, err error)
+}
+
+// NopObserver is the Observer which does nothing.
+// It can be embedded to implement only some of the Observer methods.
+type NopObserver struct{}
+
+// OnPlanned implements Observer.
+func (NopObserver) OnPlanned(Operation) {}
+
+// OnStart implements Observer.
+func (NopObserver) OnStart(Operation) {}
+
+// OnProgress implements Observer.
+func (NopObserver) OnProgress(Operation, int64) {}
+
+// OnComplete implements Observer.
+func (NopObserver) OnComplete(Operation) {}
+
+// OnSkip implements Observer.
+func (NopObserver) OnSkip(Operation) {}
+
+// OnError implements Observer.
+func (NopObserver) OnError(Operation, error) {}
+
+// progressTracker counts the bytes transferred by the parts of the given size.
+// Each part is assumed to be transferred sequentially from its beginning, so that
+// transferring the same range again, e.g. on retry, is not counted twice.
+type progressTracker struct {
+	mu       sync.Mutex
+	partSize int64
+	parts    map[int64]int64
+	done     int64
+	report   func(bytesDone int64)
+}
+
+// newProgressTracker returns nil if report is nil.
+func newProgressTracker(partSize int64, report func(bytesDone int64)) *progressTracker {
+	if report == nil {
+		return nil
+	}
+	if partSize <= 0 {
+		partSize = 1 << 62
+	}
+	return &progressTracker{
+		partSize: partSize,
+		parts:    make(map[int64]int64),
+		report:   report,
+	}
+}
+
+// add marks n bytes from the offset as transferred.
+func (t *progressTracker) add(off int64, n int) {
+	if t == nil || n <= 0 {
+		return
+	}
+	t.mu.Lock()
+	end := off + int64(n)
+	for off < end {
+		part := off / t.partSize
+		partStart := part * t.partSize
+		partEnd := min(partStart+t.partSize, end)
+		if d := partEnd - partStart; d > t.parts[part] {
+			t.done += d - t.parts[part]
+			t.parts[part] = d
+		}
+		off = partEnd
+	}
+	done := t.done
+	t.mu.Unlock()
+	t.report(done)
+}
+
+// progressReader reports the bytes read from the file.
+// It implements io.ReaderAt and io.Seeker so that the uploader can read the parts
+// without buffering.
+type progressReader struct {
+	*os.File
+	tracker *progressTracker
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	off, err := r.File.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	n, err := r.File.Read(p)
+	r.tracker.add(off, n)
+	return n, err
+}
+
+func (r *progressReader) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.File.ReadAt(p, off)
+	r.tracker.add(off, n)
+	return n, err
+}
+
+// progressWriterAt reports the bytes written to the io.WriterAt.
+type progressWriterAt struct {
+	io.WriterAt
+	tracker *progressTracker
+}
+
+func (w *progressWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	n, err := w.WriterAt.WriteAt(p, off)
+	w.tracker.add(off, n)
+	return n, err
+}
@@ -0,0 +1,74 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProgressTracker(t *testing.T) {
+	type write struct {
+		off int64
+		n   int
+	}
+	testCases := map[string]struct {
+		partSize int64
+		writes   []write
+		expected []int64
+	}{
+		"Sequential": {
+			partSize: 10,
+			writes:   []write{{0, 4}, {4, 4}, {8, 4}, {12, 8}},
+			expected: []int64{4, 8, 12, 20},
+		},
+		"Parallel": {
+			partSize: 10,
+			writes:   []write{{10, 5}, {0, 10}, {15, 5}},
+			expected: []int64{5, 15, 20},
+		},
+		"Retry": {
+			partSize: 10,
+			writes:   []write{{0, 5}, {10, 5}, {0, 10}, {10, 5}, {15, 5}},
+			expected: []int64{5, 10, 15, 15, 20},
+		},
+		"SinglePart": {
+			partSize: 0,
+			writes:   []write{{0, 5}, {5, 5}},
+			expected: []int64{5, 10},
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			var reported []int64
+			tracker := newProgressTracker(tt.partSize, func(bytesDone int64) {
+				reported = append(reported, bytesDone)
+			})
+			for _, w := range tt.writes {
+				tracker.add(w.off, w.n)
+			}
+			if !reflect.DeepEqual(tt.expected, reported) {
+				t.Errorf("Expected %v, got %v", tt.expected, reported)
+			}
+		})
+	}
+
+	t.Run("Nil", func(t *testing.T) {
+		tracker := newProgressTracker(10, nil)
+		if tracker != nil {
+			t.Fatal("Tracker without report func should be nil")
+		}
+		tracker.add(0, 10)
+	})
+}
@@ -14,6 +14,8 @@
 package s3sync
 
 import (
+	"time"
+
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
@@ -21,6 +23,13 @@ import (
 const (
 	// Default number of parallel file sync jobs.
 	DefaultParallel = 16
+	// Default size of the objects above which multipart copy is used on S3 to S3 sync.
+	// It is the maximum object size which can be copied by a single CopyObject request.
+	DefaultCopyThreshold = 5 * 1024 * 1024 * 1024
+	// Default part size of multipart copy.
+	DefaultCopyPartSize = 512 * 1024 * 1024
+	// Default number of parallel part copy requests of each multipart copy.
+	DefaultCopyConcurrency = 5
 )
 
 // Option is a functional option type of Manager.
@@ -82,3 +91,338 @@ func WithUploaderOptions(opts ...func(*manager.Uploader)) Option {
 		m.uploaderOpts = opts
 	}
 }
+
+// WithCompareMode sets the method to detect the files to be synced.
+func WithCompareMode(mode CompareMode) Option {
+	return func(m *Manager) {
+		m.compareMode = mode
+	}
+}
+
+// WithInclude adds the glob patterns of the files to be synced.
+// Patterns are matched against the slash separated path relative to the
+// synced directory, and "*" matches any characters including "/" as aws-cli does.
+// Include and exclude filters are evaluated in the order they are added,
+// and the last matched one takes effect.
+// Since all files are included by default, WithInclude is only meaningful
+// after WithExclude.
+func WithInclude(patterns ...string) Option {
+	return func(m *Manager) {
+		for _, p := range patterns {
+			m.filters = append(m.filters, newPathFilter(p, false))
+		}
+	}
+}
+
+// WithExclude adds the glob patterns of the files not to be synced.
+// Excluded files are neither transferred nor deleted on the destination.
+// See WithInclude for the pattern syntax.
+func WithExclude(patterns ...string) Option {
+	return func(m *Manager) {
+		for _, p := range patterns {
+			m.filters = append(m.filters, newPathFilter(p, true))
+		}
+	}
+}
+
+// WithCopyThreshold sets the size of the objects above which multipart copy is used
+// on S3 to S3 sync. The size is clamped to the range from 1 byte to
+// DefaultCopyThreshold, as S3 doesn't allow CopyObject of the larger objects.
+func WithCopyThreshold(size int64) Option {
+	return func(m *Manager) {
+		m.copyThreshold = min(max(size, 1), DefaultCopyThreshold)
+	}
+}
+
+// WithCopyPartSize sets the part size of multipart copy.
+// The part size is automatically increased if the object is too large to be
+// copied by the maximum number of parts.
+func WithCopyPartSize(size int64) Option {
+	return func(m *Manager) {
+		m.copyPartSize = size
+	}
+}
+
+// WithCopyConcurrency sets the number of parallel part copy requests of each multipart copy.
+func WithCopyConcurrency(n int) Option {
+	return func(m *Manager) {
+		m.copyJobs = n
+	}
+}
+
+// WithObserver sets the Observer to receive the events of each file.
+// The observer is not notified of the start and completion of the operations
+// in dry-run mode.
+func WithObserver(o Observer) Option {
+	return func(m *Manager) {
+		m.observer = o
+	}
+}
+
+// WithLogger sets the logger of the Manager instead of the one set by SetLogger.
+// If the logger implements EventLogger, structured log events are passed to it.
+func WithLogger(l LoggerIF) Option {
+	return func(m *Manager) {
+		m.logger = l
+	}
+}
+
+// WithRetryPolicy enables the retries of the failed file operations.
+// Zero fields of the policy are filled by DefaultRetryPolicy.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(m *Manager) {
+		if p.MaxAttempts == 0 {
+			p.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+		}
+		if p.InitialBackoff == 0 {
+			p.InitialBackoff = DefaultRetryPolicy.InitialBackoff
+		}
+		if p.MaxBackoff == 0 {
+			p.MaxBackoff = DefaultRetryPolicy.MaxBackoff
+		}
+		m.retryPolicy = p
+	}
+}
+
+// WithKeepPartial keeps the partially downloaded files on failure, and resumes
+// the download from them on the next sync if the object is not modified.
+// Downloads are written to the files having ".s3sync-partial" suffix and renamed
+// to the target on completion. The local files having the suffix are not
+// synced while this option is set.
+// Without this option, downloads are written to the uniquely named temporary
+// files which are removed on failure.
+func WithKeepPartial() Option {
+	return func(m *Manager) {
+		m.keepPartial = true
+	}
+}
+
+// WithCacheControl sets Cache-Control header of the uploaded objects.
+func WithCacheControl(v string) Option {
+	return func(m *Manager) {
+		m.attrs.CacheControl = &v
+	}
+}
+
+// WithContentDisposition sets Content-Disposition header of the uploaded objects.
+func WithContentDisposition(v string) Option {
+	return func(m *Manager) {
+		m.attrs.ContentDisposition = &v
+	}
+}
+
+// WithContentEncoding sets Content-Encoding header of the uploaded objects.
+func WithContentEncoding(v string) Option {
+	return func(m *Manager) {
+		m.attrs.ContentEncoding = &v
+	}
+}
+
+// WithContentLanguage sets Content-Language header of the uploaded objects.
+func WithContentLanguage(v string) Option {
+	return func(m *Manager) {
+		m.attrs.ContentLanguage = &v
+	}
+}
+
+// WithExpires sets Expires header of the uploaded objects.
+func WithExpires(t time.Time) Option {
+	return func(m *Manager) {
+		m.attrs.Expires = &t
+	}
+}
+
+// WithMetadata adds the user-defined metadata to the uploaded objects.
+func WithMetadata(metadata map[string]string) Option {
+	return func(m *Manager) {
+		if m.attrs.Metadata == nil {
+			m.attrs.Metadata = make(map[string]string)
+		}
+		for k, v := range metadata {
+			m.attrs.Metadata[k] = v
+		}
+	}
+}
+
+// WithStorageClass sets the storage class of the uploaded and copied objects.
+// The copied objects keep the storage class of the source objects by default.
+func WithStorageClass(c types.StorageClass) Option {
+	return func(m *Manager) {
+		m.attrs.StorageClass = c
+	}
+}
+
+// WithTagging adds the tags to the uploaded objects.
+func WithTagging(tags map[string]string) Option {
+	return func(m *Manager) {
+		if m.attrs.Tags == nil {
+			m.attrs.Tags = make(map[string]string)
+		}
+		for k, v := range tags {
+			m.attrs.Tags[k] = v
+		}
+	}
+}
+
+// WithAttributesFunc adds the function to set the attributes of each uploaded object,
+// e.g. to set long cache lifetime to the assets and disable cache of index.html.
+// Functions are called in the order they are added after applying the other options.
+func WithAttributesFunc(fn AttributesFunc) Option {
+	return func(m *Manager) {
+		m.attrFuncs = append(m.attrFuncs, fn)
+	}
+}
+
+// WithReplaceMetadata applies the object attributes to the objects copied on S3 to S3 sync.
+// By default, the copied objects inherit the metadata and the tags of the source objects.
+func WithReplaceMetadata() Option {
+	return func(m *Manager) {
+		m.replaceMeta = true
+	}
+}
+
+// WithServerSideEncryption sets the server-side encryption of the uploaded and copied objects.
+// If the customer key of SSE-C is specified, it is also used to download and
+// copy the source objects.
+func WithServerSideEncryption(e ServerSideEncryption) Option {
+	return func(m *Manager) {
+		m.sse = newSSEParams(e)
+	}
+}
+
+// WithClientSideEncryption encrypts the uploaded files by AES-256-GCM with the
+// data keys provided by the KeyProvider, and decrypts the downloaded objects.
+// Size and modification time of the source files are recorded in the object
+// metadata and used to compare the files instead of the ones of the objects.
+// Partial downloads are not resumed. If WithCompression is also specified, the
+// files are compressed before the encryption.
+func WithClientSideEncryption(p KeyProvider) Option {
+	return func(m *Manager) {
+		m.keyProvider = p
+	}
+}
+
+// WithCompression compresses the uploaded files and sets Content-Encoding header.
+// Size and modification time of the source files are recorded in the object
+// metadata and used to compare the files instead of the ones of the objects.
+// The downloaded objects are decompressed as WithDecompression, so that the
+// sizes of the downloaded files match the recorded ones.
+func WithCompression(c Compression) Option {
+	return func(m *Manager) {
+		m.compression = c
+		if c != CompressionNone {
+			m.decompress = true
+		}
+	}
+}
+
+// WithDecompression decompresses the downloaded objects compressed by gzip or zstd.
+// The objects compressed by WithCompression should be downloaded with this option
+// or WithCompression to compare the sizes of the files correctly.
+// Partial downloads are not resumed.
+func WithDecompression() Option {
+	return func(m *Manager) {
+		m.decompress = true
+	}
+}
+
+// WithPreserveModTime records the modification time of the uploaded files in
+// the object metadata, and uses it to compare the files and to set the
+// modification time of the downloaded files instead of the upload time.
+// The metadata is read by HeadObject request for each object compared or downloaded.
+func WithPreserveModTime() Option {
+	return func(m *Manager) {
+		m.preserveMtime = true
+	}
+}
+
+// WithPreserveMode records the permission bits of the uploaded files in the
+// object metadata, and restores them on the downloaded files.
+// It is also applied to the files copied between local paths.
+func WithPreserveMode() Option {
+	return func(m *Manager) {
+		m.preserveMode = true
+	}
+}
+
+// WithPreserveOwner records the owner uid and gid of the uploaded files in the
+// object metadata, and restores them on the downloaded files.
+// It is also applied to the files copied between local paths.
+// Changing the owner usually requires the root privilege.
+// The owner is not recorded on the platforms other than Unix.
+func WithPreserveOwner() Option {
+	return func(m *Manager) {
+		m.preserveOwner = true
+	}
+}
+
+// WithSymlinks sets the policy to sync the symbolic links in the local directories.
+// SymlinksPreserve reads the metadata of the objects by HeadObject request on download.
+// The symbolic link given as the sync source path itself is always followed.
+func WithSymlinks(p SymlinkPolicy) Option {
+	return func(m *Manager) {
+		m.symlinks = p
+	}
+}
+
+// WithListConcurrency lists the S3 objects by n parallel ListObjectsV2 requests.
+// The keys are split into the ranges by the sub-directories of the synced
+// directory, or by the split points specified by WithListSplitPoints, and the
+// ranges are listed concurrently. It takes an additional request to list the
+// sub-directories, and is effective if the objects are distributed over them.
+func WithListConcurrency(n int) Option {
+	return func(m *Manager) {
+		m.listJobs = n
+	}
+}
+
+// WithListSplitPoints sets the names relative to the synced directory to split
+// the keys into the ranges listed concurrently, e.g. "2024/", "2025/" for the
+// objects named by the dates. The split points are used only with WithListConcurrency.
+func WithListSplitPoints(names ...string) Option {
+	return func(m *Manager) {
+		m.listSplitPoints = append(m.listSplitPoints, names...)
+	}
+}
+
+// WithInventory lists the objects of the source buckets of the S3 Inventory
+// reports from the reports instead of ListObjectsV2.
+// See Inventory for the limitations.
+func WithInventory(inv ...Inventory) Option {
+	return func(m *Manager) {
+		m.inventories = append(m.inventories, inv...)
+	}
+}
+
+// WithStateCache records the states of the synced source files in a local state
+// cache under dir, keyed by the dest URL, to speed up the incremental syncs.
+// In StateTrust mode, the dest is not listed if the state cache exists, and the
+// source files unchanged since the last sync are skipped. The dest files modified
+// or deleted by others are not synced and the dest files unknown to the state
+// cache are not deleted, so StateVerify mode should be used periodically to
+// reconcile the drift.
+// The state cache is not used in dry-run mode.
+func WithStateCache(dir string, mode StateMode) Option {
+	return func(m *Manager) {
+		m.stateDir = dir
+		m.stateMode = mode
+	}
+}
+
+// WithJournal records the progress of the sync in the checkpoint journal file.
+// If the sync is interrupted, the next sync of the same source and dest with
+// the journal skips the files already synced, and continues the multipart
+// uploads in progress from the uploaded parts. The journal is removed when the
+// sync is completed, after aborting the multipart uploads recorded in it and
+// not completed, e.g. of the files removed from the source. The uploads of the
+// compressed or encrypted files are not continued. The incomplete multipart
+// uploads are kept on failure to be continued, and remain charged if the
+// journal is abandoned, so the bucket should have the lifecycle rule of
+// AbortIncompleteMultipartUpload to clean them up.
+// The sync fails if the journal is used by another sync running at the same
+// time, or is left by the sync of another source and dest.
+func WithJournal(filename string) Option {
+	return func(m *Manager) {
+		m.journal = filename
+	}
+}
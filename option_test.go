@@ -51,6 +51,25 @@ func TestWithACL(t *testing.T) {
 	})
 }
 
+func TestWithCopyThreshold(t *testing.T) {
+	cfg := aws.Config{
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", "token"),
+		Region:       "ap-northeast-1",
+	}
+	for size, expected := range map[int64]int64{
+		-1:                       1,
+		0:                        1,
+		1024:                     1024,
+		DefaultCopyThreshold:     DefaultCopyThreshold,
+		DefaultCopyThreshold + 1: DefaultCopyThreshold,
+	} {
+		m := New(cfg, WithCopyThreshold(size))
+		if m.copyThreshold != expected {
+			t.Errorf("WithCopyThreshold(%d) must set %d, got %d", size, expected, m.copyThreshold)
+		}
+	}
+}
+
 func TestUploaderDownloaderOptions(t *testing.T) {
 	cfg := aws.Config{
 		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", "token"),
@@ -0,0 +1,120 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"errors"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/smithy-go"
+)
+
+// partialSuffix is the suffix of the partial files of the resumable downloads.
+const partialSuffix = ".s3sync-partial"
+
+func isPartialFile(name string) bool {
+	return strings.HasSuffix(name, partialSuffix)
+}
+
+// tempFiles is the set of the absolute paths of the temporary and partial
+// files being written. The local listing skips them.
+var tempFiles sync.Map
+
+// createTempFile creates a uniquely named temporary file in the directory of
+// the target. It is created with 0666 permission like os.Create, so that the
+// umask of the process is applied by the kernel.
+// It must be released by releaseTempFile after it is renamed or removed.
+func createTempFile(targetFilename string) (*os.File, error) {
+	prefix := filepath.Join(filepath.Dir(targetFilename), "."+filepath.Base(targetFilename)+".")
+	for try := 0; ; try++ {
+		name := prefix + strconv.FormatUint(uint64(rand.Uint32()), 10) + ".s3sync-tmp"
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if os.IsExist(err) && try < 10000 {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		acquireTempFile(f.Name())
+		return f, nil
+	}
+}
+
+// acquireTempFile marks the file as being written.
+func acquireTempFile(name string) {
+	tempFiles.Store(absPath(name), struct{}{})
+}
+
+// releaseTempFile unmarks the file marked by acquireTempFile.
+func releaseTempFile(name string) {
+	tempFiles.Delete(absPath(name))
+}
+
+// isTempFile returns true if the file is being written by a download.
+func isTempFile(name string) bool {
+	_, ok := tempFiles.Load(absPath(name))
+	return ok
+}
+
+func absPath(name string) string {
+	if abs, err := filepath.Abs(name); err == nil {
+		return abs
+	}
+	return name
+}
+
+// partialOffset returns the size of the partial file which can be resumed,
+// or zero if the download has to be started from the beginning.
+// The partial file kept on failure has the same modification time as the object.
+func partialOffset(filename string, file *fileInfo) int64 {
+	if file.etag == "" {
+		return 0
+	}
+	stat, err := os.Stat(filename)
+	if err != nil || !stat.Mode().IsRegular() {
+		return 0
+	}
+	if stat.Size() >= file.size || !stat.ModTime().Equal(file.lastModified) {
+		return 0
+	}
+	return stat.Size()
+}
+
+// isPreconditionFailed returns true if the error is caused by the mismatch of IfMatch
+// or the invalid Range.
+func isPreconditionFailed(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "PreconditionFailed", "InvalidRange":
+		return true
+	}
+	return false
+}
+
+// offsetWriterAt writes to the underlying io.WriterAt with the offset.
+type offsetWriterAt struct {
+	io.WriterAt
+	offset int64
+}
+
+func (w *offsetWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	return w.WriterAt.WriteAt(p, off+w.offset)
+}
@@ -0,0 +1,141 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPartialOffset(t *testing.T) {
+	t0 := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	object := &fileInfo{name: "foo", size: 10, lastModified: t0, etag: "d41d8cd98f00b204e9800998ecf8427e"}
+
+	testCases := map[string]struct {
+		size     int
+		modTime  time.Time
+		file     *fileInfo
+		expected int64
+	}{
+		"Resumable": {
+			size:     4,
+			modTime:  t0,
+			file:     object,
+			expected: 4,
+		},
+		"Modified": {
+			size:     4,
+			modTime:  t0.Add(time.Second),
+			file:     object,
+			expected: 0,
+		},
+		"NoETag": {
+			size:     4,
+			modTime:  t0,
+			file:     &fileInfo{name: "foo", size: 10, lastModified: t0},
+			expected: 0,
+		},
+		"Complete": {
+			size:     10,
+			modTime:  t0,
+			file:     object,
+			expected: 0,
+		},
+		"NotExist": {
+			size:     -1,
+			file:     object,
+			expected: 0,
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "foo"+partialSuffix)
+			if tt.size >= 0 {
+				if err := os.WriteFile(filename, make([]byte, tt.size), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chtimes(filename, tt.modTime, tt.modTime); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if offset := partialOffset(filename, tt.file); offset != tt.expected {
+				t.Errorf("Expected offset %d, got %d", tt.expected, offset)
+			}
+		})
+	}
+}
+
+func TestOffsetWriterAt(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteAt([]byte("abc"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &offsetWriterAt{WriterAt: f, offset: 3}
+	if _, err := w.WriteAt([]byte("fg"), 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.WriteAt([]byte("de"), 0); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "abcdefg" {
+		t.Errorf("Expected abcdefg, got %s", string(data))
+	}
+}
+
+func TestTempFile(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "foo")
+	f, err := createTempFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if filepath.Dir(f.Name()) != filepath.Dir(target) {
+		t.Errorf("Temporary file should be created in the target directory, got %s", f.Name())
+	}
+	if !isTempFile(f.Name()) {
+		t.Error("Temporary file should be marked")
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The permission is the same as the files created by os.Create.
+	created, err := os.Create(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer created.Close()
+	expected, err := created.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Mode().Perm() != expected.Mode().Perm() {
+		t.Errorf("Expected permission %v, got %v", expected.Mode().Perm(), stat.Mode().Perm())
+	}
+	releaseTempFile(f.Name())
+	if isTempFile(f.Name()) {
+		t.Error("Released file should not be marked")
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// OperationType is the type of the sync operation.
+type OperationType string
+
+const (
+	// OperationUpload uploads a local file to S3.
+	OperationUpload OperationType = "upload"
+	// OperationDownload downloads an S3 object to the local filesystem.
+	OperationDownload OperationType = "download"
+	// OperationCopy copies an S3 object to another S3 location,
+	// a local file to another local path, or a file between the Backends.
+	OperationCopy OperationType = "copy"
+	// OperationDelete deletes the destination file unexisting on the source.
+	OperationDelete OperationType = "delete"
+)
+
+// Reason describes why the file is selected to be synced.
+type Reason string
+
+const (
+	// ReasonMissing means that the file doesn't exist on the destination.
+	ReasonMissing Reason = "missing"
+	// ReasonSizeDiffers means that the destination file has different size.
+	ReasonSizeDiffers Reason = "size differs"
+	// ReasonNewer means that the source file is newer than the destination.
+	ReasonNewer Reason = "newer"
+	// ReasonContentDiffers means that the destination file has different contents.
+	// It is used in CompareChecksum mode.
+	ReasonContentDiffers Reason = "content differs"
+	// ReasonNotInSource means that the destination file doesn't exist on the source.
+	// It is used if WithDelete is specified.
+	ReasonNotInSource Reason = "not in source"
+)
+
+// Operation is a sync operation of a file.
+// The exported fields describe the operation, and are not used by ExecutePlan.
+type Operation struct {
+	Type OperationType
+	// Source is the S3 URL or the local path of the source file.
+	// It is empty on OperationDelete.
+	Source string
+	// Dest is the S3 URL or the local path of the destination file.
+	Dest string
+	// Size and LastModified are the attributes of the source file,
+	// or of the destination file on OperationDelete.
+	Size         int64
+	LastModified time.Time
+	Reason       Reason
+
+	op *fileOp
+}
+
+// SyncPlan is the list of the operations to sync the source to the dest.
+// The plan is opaque to the caller: it can be executed only by the Manager
+// which created it, and the operations can be removed from it, but the
+// operations created or modified by the caller are not executed as described.
+type SyncPlan struct {
+	Source     string
+	Dest       string
+	Operations []Operation
+
+	manager *Manager
+	// filtered is the operations of all files including the skipped ones
+	// in order of the names, which are recorded in the state cache on execution.
+	filtered []*fileOp
+}
+
+var (
+	errUnplannedOperation = errors.New("operation is not created by Plan")
+	errForeignPlan        = errors.New("plan is not created by this Manager")
+)
+
+// Plan lists the operations required to sync the files between s3 and local disks
+// without modifying anything.
+// The returned plan can be executed by ExecutePlan.
+func (m *Manager) Plan(ctx context.Context, source, dest string) (*SyncPlan, error) {
+	pair, err := m.parseSyncPair(source, dest)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if m.stateDir != "" && !m.dryrun {
+		// The state cache is read to plan as Sync does, but not updated.
+		if pair.state, err = m.openSyncState(pair); err != nil {
+			return nil, err
+		}
+		defer pair.state.close()
+	}
+
+	plan := &SyncPlan{Source: source, Dest: dest, manager: m}
+	errs := &multiErr{}
+	for op := range m.filterOps(ctx, pair) {
+		if op.err != nil {
+			errs.Append(pair.opError(op, op.err))
+			continue
+		}
+		plan.filtered = append(plan.filtered, op)
+		if op.op != opSkip {
+			plan.Operations = append(plan.Operations, pair.operation(op))
+		}
+	}
+	if err := errs.SyncErrOrNil(); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// ExecutePlan executes the operations of the plan returned by Plan of the same Manager.
+// Changes made after the planning are not taken into account.
+// The state cache and the journal are used and updated as Sync does.
+// The context will be used for operation cancellation.
+func (m *Manager) ExecutePlan(ctx context.Context, plan *SyncPlan) error {
+	if plan.manager != m {
+		return errForeignPlan
+	}
+	pair, err := m.parseSyncPair(plan.Source, plan.Dest)
+	if err != nil {
+		return err
+	}
+	return m.run(ctx, pair, func(ctx context.Context) chan *fileOp {
+		return plan.ops(ctx, pair)
+	})
+}
+
+// ops returns a channel which receives the operations of the plan, and the
+// skipped ones to be recorded in the state cache in order of the names.
+// The operations removed from the plan are not recorded to be synced by the
+// next sync, except the deletions, which are recorded as the dest files are kept.
+func (plan *SyncPlan) ops(ctx context.Context, p *syncPair) chan *fileOp {
+	c := make(chan *fileOp)
+	go func() {
+		defer close(c)
+		send := func(op *fileOp) bool {
+			select {
+			case c <- op:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		planned := make(map[*fileOp]bool, len(plan.Operations))
+		for _, o := range plan.Operations {
+			if o.op == nil {
+				if !send(&fileOp{fileInfo: &fileInfo{err: errUnplannedOperation}}) {
+					return
+				}
+				continue
+			}
+			planned[o.op] = true
+		}
+		for _, op := range plan.filtered {
+			switch {
+			case op.op == opSkip:
+			case !planned[op]:
+				if op.op == opDelete {
+					p.state.record(op)
+				}
+				continue
+			case p.journal.completed(op):
+				// Completed by the interrupted execution.
+				op = &fileOp{fileInfo: op.fileInfo, op: opSkip}
+			}
+			p.state.record(op)
+			if !send(op) {
+				return
+			}
+		}
+	}()
+	return c
+}
+
+// operation converts the fileOp to Operation.
+func (p *syncPair) operation(op *fileOp) Operation {
+	o := Operation{
+		Size:         op.size,
+		LastModified: op.lastModified,
+		Reason:       op.reason,
+		op:           op,
+	}
+	if p.destBackend != nil {
+		o.Type, o.Source, o.Dest = OperationCopy, joinName(p.source, op.name), joinName(p.dest, op.name)
+		if op.op == opDelete {
+			o.Type, o.Source = OperationDelete, ""
+		}
+		return o
+	}
+	switch {
+	case p.sourceS3 != nil && p.destS3 != nil && op.op != opDelete:
+		o.Dest = p.destS3.joinedURL(op.name)
+	case p.destS3 != nil:
+		o.Dest = remoteTarget(op.fileInfo, p.destS3).String()
+	default:
+		o.Dest = localTarget(op.fileInfo, p.dest)
+	}
+	if p.sourceS3 != nil {
+		o.Source = "s3://" + p.sourceS3.bucket + "/" + op.objectKey()
+	} else {
+		o.Source = op.path
+	}
+
+	switch {
+	case op.op == opDelete:
+		o.Type = OperationDelete
+		o.Source = ""
+	case p.sourceS3 != nil && p.destS3 != nil, p.sourceS3 == nil && p.destS3 == nil:
+		o.Type = OperationCopy
+	case p.sourceS3 != nil:
+		o.Type = OperationDownload
+	default:
+		o.Type = OperationUpload
+	}
+	return o
+}
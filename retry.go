@@ -0,0 +1,108 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+)
+
+// RetryPolicy configures the retries of the failed file operations.
+// Each request to S3 is also retried by the AWS SDK according to its own configuration,
+// and RetryPolicy retries the whole transfer of the file, e.g. when the connection
+// is reset in the middle of the transfer.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of the attempts of each file operation
+	// including the first one. 1 disables the retries.
+	MaxAttempts int
+	// InitialBackoff is the maximum delay before the first retry.
+	// It is doubled on each retry up to MaxBackoff.
+	// The actual delay is randomly chosen between zero and the maximum delay.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Retryable returns true if the operation failed by the error should be retried.
+	// IsRetryableError is used if nil.
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy has the values used by WithRetryPolicy for the
+// unspecified (zero) fields.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Second,
+	MaxBackoff:     20 * time.Second,
+}
+
+// IsRetryableError returns true if the error is considered as transient.
+// Throttling, server errors (5xx) and network errors are retryable,
+// and other errors like AccessDenied and NoSuchBucket are not.
+func IsRetryableError(err error) bool {
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		// Connection is closed in the middle of the body.
+		return true
+	}
+	return retry.IsErrorRetryables(retry.DefaultRetryables).IsErrorRetryable(err) == aws.TrueTernary
+}
+
+// retryable returns true if the operation failed by the error should be retried.
+func (p *RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryableError(err)
+}
+
+// backoff returns the delay before the n-th retry.
+func (p *RetryPolicy) backoff(n int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < n && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d + 1)
+}
+
+// withRetry calls fn and retries it according to the RetryPolicy.
+// e is used to log the retries.
+func (m *Manager) withRetry(ctx context.Context, e LogEvent, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= m.retryPolicy.MaxAttempts || !m.retryPolicy.retryable(err) {
+			return err
+		}
+		delay := m.retryPolicy.backoff(attempt)
+		e.Level, e.Err = slog.LevelWarn, err
+		e.Message = fmt.Sprintf("%s failed, retrying in %v", e.Operation, delay)
+		m.log(e, false)
+		m.incrementRetries()
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
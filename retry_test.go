@@ -0,0 +1,155 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type apiError struct {
+	code   string
+	status int
+}
+
+func (e *apiError) Error() string       { return e.code }
+func (e *apiError) ErrorCode() string   { return e.code }
+func (e *apiError) HTTPStatusCode() int { return e.status }
+
+func TestIsRetryableError(t *testing.T) {
+	testCases := map[string]struct {
+		err      error
+		expected bool
+	}{
+		"SlowDown":           {&apiError{"SlowDown", 503}, true},
+		"InternalError":      {&apiError{"InternalError", 500}, true},
+		"ConnectionReset":    {&net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, true},
+		"UnexpectedEOF":      {fmt.Errorf("read body: %w", io.ErrUnexpectedEOF), true},
+		"AccessDenied":       {&apiError{"AccessDenied", 403}, false},
+		"NoSuchBucket":       {&types.NoSuchBucket{}, false},
+		"Canceled":           {context.Canceled, false},
+		"LocalFilesystem":    {&net.AddrError{Err: "test"}, false},
+		"WrappedByFileError": {&FileError{Err: &apiError{"SlowDown", 503}}, true},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			if retryable := IsRetryableError(tt.err); retryable != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, retryable)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := &RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	for n, max := range map[int]time.Duration{
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		4: 5 * time.Second,
+		9: 5 * time.Second,
+	} {
+		for i := 0; i < 100; i++ {
+			if d := p.backoff(n); d < 0 || d > max {
+				t.Fatalf("Backoff before the retry %d must be in [0, %v], got %v", n, max, d)
+			}
+		}
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	errRetryable := &apiError{"SlowDown", 503}
+	errFatal := &apiError{"AccessDenied", 403}
+
+	testCases := map[string]struct {
+		opts             []Option
+		errs             []error
+		expectedErr      error
+		expectedAttempts int
+	}{
+		"NoRetryByDefault": {
+			errs:             []error{errRetryable, nil},
+			expectedErr:      errRetryable,
+			expectedAttempts: 1,
+		},
+		"Succeeded": {
+			opts:             []Option{WithRetryPolicy(RetryPolicy{InitialBackoff: time.Millisecond})},
+			errs:             []error{errRetryable, errRetryable, nil},
+			expectedAttempts: 3,
+		},
+		"Exhausted": {
+			opts:             []Option{WithRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})},
+			errs:             []error{errRetryable, errRetryable, nil},
+			expectedErr:      errRetryable,
+			expectedAttempts: 2,
+		},
+		"Fatal": {
+			opts:             []Option{WithRetryPolicy(RetryPolicy{InitialBackoff: time.Millisecond})},
+			errs:             []error{errFatal, nil},
+			expectedErr:      errFatal,
+			expectedAttempts: 1,
+		},
+		"CustomRetryable": {
+			opts: []Option{WithRetryPolicy(RetryPolicy{
+				InitialBackoff: time.Millisecond,
+				Retryable:      func(err error) bool { return err == errFatal },
+			})},
+			errs:             []error{errFatal, nil},
+			expectedAttempts: 2,
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			m := New(getSession(), tt.opts...)
+			var attempts int
+			err := m.withRetry(context.Background(), LogEvent{}, func() error {
+				err := tt.errs[attempts]
+				attempts++
+				return err
+			})
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tt.expectedErr, err)
+			}
+			if attempts != tt.expectedAttempts {
+				t.Errorf("Expected %d attempts, got %d", tt.expectedAttempts, attempts)
+			}
+			if n := m.GetStatistics().Retries; n != int64(attempts-1) {
+				t.Errorf("Expected %d retries, got %d", attempts-1, n)
+			}
+		})
+	}
+
+	t.Run("Canceled", func(t *testing.T) {
+		m := New(getSession(), WithRetryPolicy(RetryPolicy{InitialBackoff: time.Hour, MaxBackoff: time.Hour}))
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		var attempts int
+		err := m.withRetry(ctx, LogEvent{}, func() error {
+			attempts++
+			return errRetryable
+		})
+		if err != errRetryable || attempts != 1 {
+			t.Errorf("Retry should be stopped by the context cancellation, err: %v, attempts: %d", err, attempts)
+		}
+	})
+}
@@ -14,7 +14,9 @@ package s3sync
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/url"
 	"os"
 	"path"
@@ -22,6 +24,7 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
@@ -30,29 +33,64 @@ import (
 	"github.com/gabriel-vasile/mimetype"
 )
 
-// s3API defines the subset of s3.Client methods used by Manager, for testability.
-type s3API interface {
-	manager.DownloadAPIClient
-	manager.UploadAPIClient
-	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
-	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+// S3API is the subset of s3.Client methods used by Manager.
+// It can be implemented by a wrapped client or a mock, e.g. fakes3.Client.
+type S3API interface {
 	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error)
 	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
 	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	ListParts(ctx context.Context, params *s3.ListPartsInput, optFns ...func(*s3.Options)) (*s3.ListPartsOutput, error)
+	UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
 }
 
+var _ S3API = (*s3.Client)(nil)
+
 // Manager manages the sync operation.
 type Manager struct {
-	s3             s3API
-	nJobs          int
-	del            bool
-	dryrun         bool
-	acl            types.ObjectCannedACL
-	guessMime      bool
-	contentType    *string
-	downloaderOpts []func(*manager.Downloader)
-	uploaderOpts   []func(*manager.Uploader)
-	statistics     SyncStatistics
+	s3              S3API
+	nJobs           int
+	del             bool
+	dryrun          bool
+	acl             types.ObjectCannedACL
+	guessMime       bool
+	contentType     *string
+	compareMode     CompareMode
+	filters         []pathFilter
+	downloaderOpts  []func(*manager.Downloader)
+	uploaderOpts    []func(*manager.Uploader)
+	copyThreshold   int64
+	copyPartSize    int64
+	copyJobs        int
+	logger          LoggerIF
+	retryPolicy     RetryPolicy
+	keepPartial     bool
+	observer        Observer
+	attrs           ObjectAttributes
+	attrFuncs       []AttributesFunc
+	replaceMeta     bool
+	sse             *sseParams
+	keyProvider     KeyProvider
+	preserveMtime   bool
+	preserveMode    bool
+	preserveOwner   bool
+	compression     Compression
+	decompress      bool
+	symlinks        SymlinkPolicy
+	listJobs        int
+	listSplitPoints []string
+	inventories     []Inventory
+	stateDir        string
+	stateMode       StateMode
+	journal         string
+	statistics      SyncStatistics
 }
 
 // SyncStatistics captures the sync statistics.
@@ -60,6 +98,7 @@ type SyncStatistics struct {
 	Bytes        int64
 	Files        int64
 	DeletedFiles int64
+	Retries      int64
 	mutex        sync.RWMutex
 }
 
@@ -68,29 +107,60 @@ type operation int
 const (
 	opUpdate operation = iota
 	opDelete
+	opSkip
 )
 
 type fileInfo struct {
-	name           string
-	err            error
-	path           string
-	size           int64
-	lastModified   time.Time
-	singleFile     bool
-	existsInSource bool
+	name         string
+	err          error
+	path         string
+	size         int64
+	lastModified time.Time
+	singleFile   bool
+
+	// Following fields are available only if the attributes are preserved.
+	mode        os.FileMode
+	owner       *fileOwner
+	hasMetadata bool
+
+	// symlink is true if the file is a symbolic link preserved by SymlinksPreserve.
+	symlink bool
+
+	// Following fields are available only on S3 objects.
+	etag              string
+	checksumAlgorithm []types.ChecksumAlgorithm
+	checksumType      types.ChecksumType
+	storageClass      types.ObjectStorageClass
+
+	// inventoried is true if the file is listed from the inventory report,
+	// so that the attributes may be outdated.
+	inventoried bool
 }
 
 type fileOp struct {
 	*fileInfo
-	op operation
+	op     operation
+	reason Reason
+	// dest is the dest file compared to the source, if it exists.
+	dest *fileInfo
 }
 
 // New returns a new Manager.
 func New(cfg aws.Config, options ...Option) *Manager {
+	return NewWithClient(s3.NewFromConfig(cfg), options...)
+}
+
+// NewWithClient returns a new Manager using the given S3 client.
+// It can be used to pass the client created with custom s3.Options or
+// wrapped with additional middlewares.
+func NewWithClient(client S3API, options ...Option) *Manager {
 	m := &Manager{
-		s3:        s3.NewFromConfig(cfg),
-		nJobs:     DefaultParallel,
-		guessMime: true,
+		s3:            client,
+		nJobs:         DefaultParallel,
+		guessMime:     true,
+		copyThreshold: DefaultCopyThreshold,
+		copyPartSize:  DefaultCopyPartSize,
+		copyJobs:      DefaultCopyConcurrency,
 	}
 	for _, o := range options {
 		o(m)
@@ -101,180 +171,359 @@ func New(cfg aws.Config, options ...Option) *Manager {
 // Sync syncs the files between s3 and local disks.
 // The context will be used for operation cancellation.
 func (m *Manager) Sync(ctx context.Context, source, dest string) error {
-	sourceURL, err := url.Parse(source)
+	pair, err := m.parseSyncPair(source, dest)
 	if err != nil {
 		return err
 	}
 
-	destURL, err := url.Parse(dest)
-	if err != nil {
-		return err
-	}
+	return m.run(ctx, pair, func(ctx context.Context) chan *fileOp {
+		return m.filterOps(ctx, pair)
+	})
+}
 
+// run executes the operations of the pair returned by ops with the state cache
+// and the journal, which are committed after the execution.
+func (m *Manager) run(ctx context.Context, pair *syncPair, ops func(context.Context) chan *fileOp) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	chJob := make(chan func())
-	var wg sync.WaitGroup
-	for i := 0; i < m.nJobs; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for job := range chJob {
-				job()
-			}
-		}()
-	}
-	defer func() {
-		close(chJob)
-		wg.Wait()
-	}()
-
-	if isS3URL(sourceURL) {
-		sourceS3Path, err := urlToS3Path(sourceURL)
-		if err != nil {
+	var err error
+	if m.stateDir != "" && !m.dryrun {
+		if pair.state, err = m.openSyncState(pair); err != nil {
 			return err
 		}
-		if isS3URL(destURL) {
-			destS3Path, err := urlToS3Path(destURL)
-			if err != nil {
-				return err
-			}
-			return m.syncS3ToS3(ctx, chJob, sourceS3Path, destS3Path)
-		}
-		return m.syncS3ToLocal(ctx, chJob, sourceS3Path, dest)
+		defer pair.state.close()
 	}
-
-	if isS3URL(destURL) {
-		destS3Path, err := urlToS3Path(destURL)
-		if err != nil {
+	if m.journal != "" && !m.dryrun {
+		if pair.journal, err = m.openJournal(pair); err != nil {
 			return err
 		}
-		return m.syncLocalToS3(ctx, chJob, source, destS3Path)
 	}
 
-	return errors.New("local to local sync is not supported")
+	err = m.runOps(ctx, pair, ops(ctx))
+	// The state cache is updated excluding the failed and the deleted files.
+	if stateErr := pair.state.commit(ctx); err == nil {
+		err = stateErr
+	}
+	// The journal is kept to resume the sync unless it is completed.
+	if journalErr := pair.journal.finish(ctx, m.s3, err == nil && ctx.Err() == nil); err == nil {
+		err = journalErr
+	}
+	return err
 }
 
 // GetStatistics returns the structure that contains the sync statistics
 func (m *Manager) GetStatistics() SyncStatistics {
 	m.statistics.mutex.Lock()
 	defer m.statistics.mutex.Unlock()
-	return SyncStatistics{Bytes: m.statistics.Bytes, Files: m.statistics.Files, DeletedFiles: m.statistics.DeletedFiles}
+	return SyncStatistics{
+		Bytes:        m.statistics.Bytes,
+		Files:        m.statistics.Files,
+		DeletedFiles: m.statistics.DeletedFiles,
+		Retries:      m.statistics.Retries,
+	}
 }
 
 func isS3URL(url *url.URL) bool {
 	return url.Scheme == "s3"
 }
 
-func (m *Manager) syncS3ToS3(ctx context.Context, chJob chan func(), sourcePath *s3Path, destPath *s3Path) error {
-	wg := &sync.WaitGroup{}
-	errs := &multiErr{}
-	for source := range filterFilesForSync(
-		m.listS3Files(ctx, sourcePath), m.listS3Files(ctx, destPath), m.del,
-	) {
-		wg.Add(1)
-		source := source
-		chJob <- func() {
-			defer wg.Done()
-			if source.err != nil {
-				errs.Append(source.err)
-				return
-			}
-			switch source.op {
-			case opUpdate:
-				if err := m.copyS3ToS3(ctx, source.fileInfo, sourcePath, destPath); err != nil {
-					errs.Append(err)
-				}
-			}
+// syncPair is the source and the destination of the sync.
+// S3 paths are nil if they are local paths or registered Backends.
+// If either of them is a registered Backend, the files are transferred through
+// the Backends, where S3 and local paths are accessed by the native functions.
+type syncPair struct {
+	source, dest               string
+	sourceS3, destS3           *s3Path
+	sourceBackend, destBackend Backend
+	// state is the state cache updated by the sync.
+	state *syncState
+	// journal is the checkpoint journal of the sync.
+	journal *syncJournal
+}
+
+func (m *Manager) parseSyncPair(source, dest string) (*syncPair, error) {
+	sourceURL, err := url.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	destURL, err := url.Parse(dest)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &syncPair{source: source, dest: dest}
+	if isS3URL(sourceURL) {
+		if p.sourceS3, err = urlToS3Path(sourceURL); err != nil {
+			return nil, err
+		}
+	}
+	if isS3URL(destURL) {
+		if p.destS3, err = urlToS3Path(destURL); err != nil {
+			return nil, err
 		}
 	}
-	wg.Wait()
 
-	return errs.ErrOrNil()
+	_, sourceRegistered := lookupBackend(sourceURL.Scheme)
+	_, destRegistered := lookupBackend(destURL.Scheme)
+	if sourceRegistered || destRegistered {
+		if p.sourceBackend, err = m.backendOf(source, sourceURL, p.sourceS3); err != nil {
+			return nil, err
+		}
+		if p.destBackend, err = m.backendOf(dest, destURL, p.destS3); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
 
+// listFiles lists the files of the registered Backend, of the S3 path, or of the
+// local path if the others are nil.
+// The objects are listed from the inventory report if it is not nil.
+func (m *Manager) listFiles(ctx context.Context, b Backend, s3Path *s3Path, localPath string, report *inventoryReport) chan *fileInfo {
+	switch {
+	case isRegisteredBackend(b):
+		return m.listBackendFiles(ctx, b, localPath)
+	case report != nil:
+		return m.listInventoryFiles(ctx, s3Path, report)
+	case s3Path != nil:
+		return m.listS3Files(ctx, s3Path)
+	}
+	return m.listLocalFiles(ctx, localPath)
 }
 
-func (m *Manager) syncLocalToS3(ctx context.Context, chJob chan func(), sourcePath string, destPath *s3Path) error {
-	wg := &sync.WaitGroup{}
-	errs := &multiErr{}
-	for source := range filterFilesForSync(
-		listLocalFiles(ctx, sourcePath), m.listS3Files(ctx, destPath), m.del,
-	) {
-		wg.Add(1)
-		source := source
-		chJob <- func() {
-			defer wg.Done()
-			if source.err != nil {
-				errs.Append(source.err)
-				return
-			}
-			switch source.op {
-			case opUpdate:
-				if err := m.upload(ctx, source.fileInfo, sourcePath, destPath); err != nil {
-					errs.Append(err)
-				}
-			case opDelete:
-				if err := m.deleteRemote(ctx, source.fileInfo, destPath); err != nil {
-					errs.Append(err)
+// filterOps returns a channel which receives the operations required to sync the pair,
+// and the skipped ones to be recorded by the receiver.
+// The observer is notified of the planned and skipped files.
+func (m *Manager) filterOps(ctx context.Context, p *syncPair) chan *fileOp {
+	ops := m.filterFileOps(ctx, p)
+	c := make(chan *fileOp)
+	go func() {
+		defer close(c)
+		for op := range ops {
+			p.state.record(op)
+			switch {
+			case op.err != nil:
+			case op.op == opSkip:
+				if m.observer != nil {
+					m.observer.OnSkip(p.operation(op))
 				}
+			case m.observer != nil:
+				m.observer.OnPlanned(p.operation(op))
+			}
+			select {
+			case c <- op:
+			case <-ctx.Done():
+				return
 			}
 		}
+	}()
+	return c
+}
+
+// filterFileOps returns a channel which receives the operations required to sync
+// the files of the pair.
+func (m *Manager) filterFileOps(ctx context.Context, p *syncPair) chan *fileOp {
+	sourceReport, err := m.openInventory(ctx, p.sourceS3)
+	var destReport *inventoryReport
+	if err == nil {
+		destReport, err = m.openInventory(ctx, p.destS3)
+	}
+	if err != nil {
+		c := make(chan *fileOp, 1)
+		c <- &fileOp{fileInfo: &fileInfo{err: err}}
+		close(c)
+		return c
 	}
-	wg.Wait()
 
-	return errs.ErrOrNil()
+	destFiles, err := p.state.trustedFiles(ctx, p)
+	if err != nil {
+		c := make(chan *fileOp, 1)
+		c <- &fileOp{fileInfo: &fileInfo{err: &ListError{Path: p.state.filename, Err: err}}}
+		close(c)
+		return c
+	}
+	compare := m.symlinkComparator(m.fileComparator(ctx, p.sourceS3, p.destS3))
+	if p.sourceBackend != nil {
+		compare = m.backendComparator(ctx, p)
+	}
+	// destListed is true if the dest objects are listed from the report.
+	destListed := destReport != nil && destFiles == nil
+	if destFiles != nil {
+		// The dest files are recorded states of the source files at the last sync.
+		compare = compareWithState
+	} else {
+		destFiles = m.checkStateDrift(ctx, p.state, m.listFiles(ctx, p.destBackend, p.destS3, p.dest, destReport))
+	}
+
+	sourceFiles := m.listFiles(ctx, p.sourceBackend, p.sourceS3, p.source, sourceReport)
+	var ops chan *fileOp
+	if sourceReport == nil && destReport == nil {
+		ops = filterFilesForSync(ctx, sourceFiles, destFiles, m.del, p.journal.compare(compare))
+	} else {
+		// The operations planned from the reports are compared again by the current
+		// states of the objects, and then the files completed by the interrupted
+		// sync are skipped.
+		ops = m.reconcileInventoryOps(ctx, p,
+			filterFilesForSync(ctx, sourceFiles, destFiles, m.del, compare),
+			sourceReport != nil, destListed, p.journal.compare(compare),
+		)
+	}
+	if m.writesSymlinks(p) {
+		ops = checkNestedFiles(ctx, ops)
+	}
+	return ops
 }
 
-// syncS3ToLocal syncs the given s3 path to the given local path.
-func (m *Manager) syncS3ToLocal(ctx context.Context, chJob chan func(), sourcePath *s3Path, destPath string) error {
-	wg := &sync.WaitGroup{}
+// runOps runs the operations in parallel and returns the errors occurred.
+func (m *Manager) runOps(ctx context.Context, p *syncPair, ops chan *fileOp) error {
+	chJob := make(chan *fileOp)
+	var wg sync.WaitGroup
 	errs := &multiErr{}
-	for source := range filterFilesForSync(
-		m.listS3Files(ctx, sourcePath), listLocalFiles(ctx, destPath), m.del,
-	) {
+	for i := 0; i < m.nJobs; i++ {
 		wg.Add(1)
-		source := source
-		chJob <- func() {
+		go func() {
 			defer wg.Done()
-			if source.err != nil {
-				errs.Append(source.err)
-				return
-			}
-			switch source.op {
-			case opUpdate:
-				if err := m.download(ctx, source.fileInfo, sourcePath, destPath); err != nil {
-					errs.Append(err)
-				}
-			case opDelete:
-				if err := m.deleteLocal(ctx, source.fileInfo, destPath); err != nil {
+			for op := range chJob {
+				if err := m.runOp(ctx, p, op); err != nil {
+					p.state.fail(op)
 					errs.Append(err)
+					continue
 				}
+				p.state.complete(op)
+				p.journal.complete(op)
 			}
+		}()
+	}
+	for op := range ops {
+		if op.op == opSkip {
+			continue
 		}
+		chJob <- op
 	}
+	close(chJob)
 	wg.Wait()
 
-	return errs.ErrOrNil()
+	return errs.SyncErrOrNil()
 }
 
-func (m *Manager) copyS3ToS3(ctx context.Context, file *fileInfo, sourcePath *s3Path, destPath *s3Path) error {
-	copySource := path.Join(sourcePath.bucket, sourcePath.bucketPrefix, file.name)
-	destinationKey := path.Join(destPath.bucketPrefix, file.name)
-
-	logf("copy: %s to %s", sourcePath.joinedURL(file.name), destPath.joinedURL(file.name))
+// runOp runs the operation, and logs and notifies the observer of its progress.
+// Nothing is run in dry-run mode.
+func (m *Manager) runOp(ctx context.Context, p *syncPair, op *fileOp) error {
+	if op.err != nil {
+		return p.opError(op, op.err)
+	}
 
+	e := p.logEvent(op)
 	if m.dryrun {
+		e.Level = slog.LevelInfo
+		m.log(e, true)
 		return nil
 	}
-
-	_, err := m.s3.CopyObject(ctx, &s3.CopyObjectInput{
-		Bucket:     &destPath.bucket,
-		CopySource: &copySource,
-		Key:        &destinationKey,
-		ACL:        m.acl,
+	m.log(e, true)
+
+	var o Operation
+	var progress func(int64)
+	if m.observer != nil {
+		o = p.operation(op)
+		m.observer.OnStart(o)
+		progress = func(bytesDone int64) {
+			m.observer.OnProgress(o, bytesDone)
+		}
+	}
+	start := time.Now()
+	err := m.withRetry(ctx, e, func() error {
+		return m.doOp(ctx, p, op, progress)
 	})
+	e.Duration = time.Since(start)
+	if err != nil {
+		e.Level, e.Message, e.Err = slog.LevelError, string(e.Operation)+" failed", err
+		m.log(e, false)
+		if m.observer != nil {
+			m.observer.OnError(o, err)
+		}
+		return p.opError(op, err)
+	}
+	e.Level, e.Message = slog.LevelInfo, string(e.Operation)+" completed"
+	m.log(e, false)
+	if m.observer != nil {
+		m.observer.OnComplete(o)
+	}
+	return nil
+}
+
+// opError returns FileError of the operation.
+// err is returned as is if the operation is not associated with any file.
+func (p *syncPair) opError(op *fileOp, err error) error {
+	if op.name == "" && !op.singleFile {
+		return err
+	}
+	o := p.operation(op)
+	return &FileError{Op: o.Type, Source: o.Source, Dest: o.Dest, Err: err}
+}
+
+// doOp runs the operation.
+// progress is called with the number of bytes transferred if it is not nil.
+func (m *Manager) doOp(ctx context.Context, p *syncPair, op *fileOp, progress func(bytesDone int64)) error {
+	if m.writesSymlinks(p) {
+		if err := checkSymlinkParents(p.dest, localTarget(op.fileInfo, p.dest)); err != nil {
+			return err
+		}
+	}
+	switch {
+	case op.op == opDelete && p.destBackend != nil:
+		return m.deleteBackendFile(ctx, p, op.fileInfo)
+	case p.destBackend != nil:
+		return m.transfer(ctx, p, op.fileInfo, progress)
+	case op.op == opDelete && p.destS3 != nil:
+		return m.deleteRemote(ctx, op.fileInfo, p.destS3)
+	case op.op == opDelete:
+		return m.deleteLocal(ctx, op.fileInfo, p.dest)
+	case p.sourceS3 != nil && p.destS3 != nil:
+		return m.copyS3ToS3(ctx, op.fileInfo, p.sourceS3, p.destS3, progress)
+	case p.sourceS3 == nil && p.destS3 == nil:
+		return m.copyLocal(ctx, op.fileInfo, p.source, p.dest, progress)
+	case p.sourceS3 != nil:
+		return m.download(ctx, op.fileInfo, p.sourceS3, p.dest, progress)
+	default:
+		return m.upload(ctx, op.fileInfo, p.source, p.destS3, p.journal, progress)
+	}
+}
 
+func (m *Manager) copyS3ToS3(ctx context.Context, file *fileInfo, sourcePath *s3Path, destPath *s3Path, progress func(int64)) error {
+	copySource := path.Join(sourcePath.bucket, file.objectKey())
+	destinationKey := path.Join(destPath.bucketPrefix, file.name)
+
+	attrs := m.objectAttributes(file.name)
+
+	var err error
+	if file.size > m.copyThreshold {
+		// CopyObject doesn't support the objects larger than 5 GiB.
+		tracker := newProgressTracker(m.partSizeForCopy(file.size), progress)
+		err = m.copyMultipart(ctx, sourcePath.bucket, file.objectKey(), copySource, destPath.bucket, destinationKey, attrs, tracker)
+	} else {
+		input := &s3.CopyObjectInput{
+			Bucket:       &destPath.bucket,
+			CopySource:   &copySource,
+			Key:          &destinationKey,
+			ACL:          m.acl,
+			StorageClass: attrs.StorageClass,
+		}
+		if input.StorageClass == "" {
+			input.StorageClass = types.StorageClass(file.storageClass)
+		}
+		m.sse.copyObject(input)
+		if m.replaceMeta {
+			err = m.replaceCopyMetadata(ctx, sourcePath.bucket, file.objectKey(), attrs, input)
+		}
+		if err == nil {
+			_, err = m.s3.CopyObject(ctx, input)
+		}
+		if err == nil {
+			newProgressTracker(0, progress).add(0, int(file.size))
+		}
+	}
 	if err != nil {
 		return err
 	}
@@ -283,71 +532,164 @@ func (m *Manager) copyS3ToS3(ctx context.Context, file *fileInfo, sourcePath *s3
 	return nil
 }
 
-func (m *Manager) download(ctx context.Context, file *fileInfo, sourcePath *s3Path, destPath string) error {
-	var targetFilename string
+// localSource returns the local filename to sync the file from.
+func localSource(file *fileInfo, sourcePath string) string {
+	if file.singleFile {
+		return sourcePath
+	}
+	return filepath.Join(sourcePath, file.name)
+}
+
+// localTarget returns the local filename to sync the file to.
+func localTarget(file *fileInfo, destPath string) string {
 	if !strings.HasSuffix(destPath, "/") && file.singleFile {
 		// Destination path is not a directory and source is a single file.
-		targetFilename = destPath
-	} else {
-		targetFilename = filepath.Join(destPath, file.name)
+		return destPath
 	}
-	targetDir := filepath.Dir(targetFilename)
-
-	logf("download: %s to %s", sourcePath.joinedURL(file.name), targetFilename)
+	return filepath.Join(destPath, file.name)
+}
 
-	if m.dryrun {
-		return nil
+// remoteTarget returns the S3 path to sync the file to.
+func remoteTarget(file *fileInfo, destPath *s3Path) *s3Path {
+	destFile := *destPath
+	if strings.HasSuffix(destPath.bucketPrefix, "/") || destPath.bucketPrefix == "" || !file.singleFile {
+		// If source is a single file and destination is not a directory, use destination URL as is.
+		destFile.bucketPrefix = path.Join(destPath.bucketPrefix, file.name)
 	}
+	return &destFile
+}
+
+// download downloads the file to a temporary file and renames it to the target,
+// so that the target is never left truncated.
+func (m *Manager) download(ctx context.Context, file *fileInfo, sourcePath *s3Path, destPath string, progress func(int64)) error {
+	targetFilename := localTarget(file, destPath)
+	targetDir := filepath.Dir(targetFilename)
 
 	if err := os.MkdirAll(targetDir, 0755); err != nil {
 		return err
 	}
 
-	writer, err := os.Create(targetFilename)
-	if err != nil {
-		return err
+	// The listed key is read, as the objects having the prefix but not placed
+	// under the directory are not named by the keys joined to the prefix.
+	sourceFile := file.objectKey()
+
+	if m.keyProvider != nil || m.decompress {
+		return m.downloadDecoded(ctx, file, sourcePath.bucket, sourceFile, targetFilename, progress)
 	}
 
-	defer writer.Close()
+	source := file
+	if (m.preservesAttributes() || m.symlinks == SymlinksPreserve) && !file.hasMetadata {
+		f := *file
+		if err := m.headFileMetadata(ctx, sourcePath.bucket, &f); err != nil {
+			return err
+		}
+		source = &f
+	}
+	if m.symlinks == SymlinksPreserve && source.symlink {
+		return m.downloadSymlink(ctx, source, sourcePath.bucket, sourceFile, targetFilename, progress)
+	}
+	modTime := file.lastModified
+	if m.preserveMtime {
+		modTime = source.lastModified
+	}
 
-	var sourceFile string
-	if file.singleFile {
-		sourceFile = file.name
+	var partialFilename string
+	var offset int64
+	if m.keepPartial {
+		// The partial file has the fixed name to be resumed by the next sync.
+		partialFilename = targetFilename + partialSuffix
+		acquireTempFile(partialFilename)
+		offset = partialOffset(partialFilename, file)
 	} else {
-		sourceFile = path.Join(sourcePath.bucketPrefix, file.name)
+		tmp, err := createTempFile(targetFilename)
+		if err != nil {
+			return err
+		}
+		tmp.Close()
+		partialFilename = tmp.Name()
 	}
-
-	c := manager.NewDownloader(m.s3, m.downloaderOpts...)
-	written, err := c.Download(ctx, writer, &s3.GetObjectInput{
-		Bucket: &sourcePath.bucket,
-		Key:    &sourceFile,
-	})
-	if err != nil {
-		return err
+	defer releaseTempFile(partialFilename)
+	written, err := m.downloadToFile(ctx, file, sourcePath.bucket, sourceFile, partialFilename, offset, progress)
+	if err != nil && offset > 0 && isPreconditionFailed(err) {
+		// The object is modified after the partial file is downloaded.
+		offset = 0
+		written, err = m.downloadToFile(ctx, file, sourcePath.bucket, sourceFile, partialFilename, offset, progress)
 	}
-	m.updateFileTransferStatistics(written)
-	err = os.Chtimes(targetFilename, file.lastModified, file.lastModified)
 	if err != nil {
+		if m.keepPartial && written+offset > 0 {
+			// Mark the partial file as the one of this version of the object.
+			if chErr := os.Chtimes(partialFilename, file.lastModified, file.lastModified); chErr == nil {
+				return err
+			}
+		}
+		os.Remove(partialFilename)
 		return err
 	}
 
+	if err := os.Chtimes(partialFilename, modTime, modTime); err != nil {
+		os.Remove(partialFilename)
+		return err
+	}
+	if err := os.Rename(partialFilename, targetFilename); err != nil {
+		os.Remove(partialFilename)
+		return err
+	}
+	if err := m.restoreFileAttributes(targetFilename, source); err != nil {
+		return err
+	}
+	m.updateFileTransferStatistics(written)
 	return nil
 }
 
-func (m *Manager) deleteLocal(ctx context.Context, file *fileInfo, destPath string) error {
-	var targetFilename string
-	if !strings.HasSuffix(destPath, "/") && file.singleFile {
-		// Destination path is not a directory and source is a single file.
-		targetFilename = destPath
-	} else {
-		targetFilename = filepath.Join(destPath, file.name)
+// downloadToFile downloads the object from the offset to the file and syncs it to the disk.
+// The file is truncated if offset is zero.
+func (m *Manager) downloadToFile(ctx context.Context, file *fileInfo, bucket, key, filename string, offset int64, progress func(int64)) (int64, error) {
+	flag := os.O_WRONLY | os.O_CREATE
+	if offset == 0 {
+		flag |= os.O_TRUNC
 	}
+	writer, err := os.OpenFile(filename, flag, 0666)
+	if err != nil {
+		return 0, err
+	}
+	defer writer.Close()
 
-	logf("delete: %s", targetFilename)
+	input := &s3.GetObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+	}
+	m.sse.getObject(input)
+	c := manager.NewDownloader(m.s3, m.downloaderOpts...)
+	partSize := c.PartSize
+	if offset > 0 {
+		// Range request is downloaded by a single request.
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+		input.IfMatch = aws.String(`"` + file.etag + `"`)
+		partSize = 0
+	}
 
-	if m.dryrun {
-		return nil
+	var w io.WriterAt = writer
+	if tracker := newProgressTracker(partSize, progress); tracker != nil {
+		// The contents of the partial file are counted as transferred.
+		tracker.add(0, int(offset))
+		w = &progressWriterAt{WriterAt: w, tracker: tracker}
+	}
+	if offset > 0 {
+		w = &offsetWriterAt{WriterAt: w, offset: offset}
+	}
+	written, err := c.Download(ctx, w, input)
+	if err != nil {
+		return written, err
 	}
+	if err := writer.Sync(); err != nil {
+		return written, err
+	}
+	return written, writer.Close()
+}
+
+func (m *Manager) deleteLocal(ctx context.Context, file *fileInfo, destPath string) error {
+	targetFilename := localTarget(file, destPath)
+
 	err := os.Remove(targetFilename)
 	if err != nil {
 		return err
@@ -356,37 +698,20 @@ func (m *Manager) deleteLocal(ctx context.Context, file *fileInfo, destPath stri
 	return nil
 }
 
-func (m *Manager) upload(ctx context.Context, file *fileInfo, sourcePath string, destPath *s3Path) error {
-	var sourceFilename string
-	if file.singleFile {
-		sourceFilename = sourcePath
-	} else {
-		sourceFilename = filepath.Join(sourcePath, file.name)
-	}
-
-	destFile := *destPath
-	if strings.HasSuffix(destPath.bucketPrefix, "/") || destPath.bucketPrefix == "" || !file.singleFile {
-		// If source is a single file and destination is not a directory, use destination URL as is.
-		destFile.bucketPrefix = path.Join(destPath.bucketPrefix, file.name)
-	}
+func (m *Manager) upload(ctx context.Context, file *fileInfo, sourcePath string, destPath *s3Path, journal *syncJournal, progress func(int64)) error {
+	sourceFilename := localSource(file, sourcePath)
 
-	logf("upload: %s to %s", file.name, destFile.String())
+	destFile := remoteTarget(file, destPath)
 
-	if m.dryrun {
-		return nil
+	if file.symlink {
+		return m.uploadSymlink(ctx, file, sourceFilename, destFile, progress)
 	}
 
-	var contentType *string
-	switch {
-	case m.contentType != nil:
-		contentType = m.contentType
-	case m.guessMime:
-		mime, err := mimetype.DetectFile(sourceFilename)
-		if err != nil {
-			return err
-		}
-		s := mime.String()
-		contentType = &s
+	contentType, err := m.uploadContentType(func() (*mimetype.MIME, error) {
+		return mimetype.DetectFile(sourceFilename)
+	})
+	if err != nil {
+		return err
 	}
 
 	reader, err := os.Open(sourceFilename)
@@ -396,35 +721,89 @@ func (m *Manager) upload(ctx context.Context, file *fileInfo, sourcePath string,
 
 	defer reader.Close()
 
-	_, err = manager.NewUploader(
-		m.s3,
-		m.uploaderOpts...,
-	).Upload(ctx, &s3.PutObjectInput{
-		Bucket:      &destFile.bucket,
-		Key:         &destFile.bucketPrefix,
-		ACL:         m.acl,
-		Body:        reader,
-		ContentType: contentType,
-	})
-	if err != nil {
+	var body io.Reader = reader
+	var bodyAt io.ReaderAt = reader
+	tracker := newProgressTracker(m.uploadPartSize(file.size), progress)
+	if tracker != nil {
+		r := &progressReader{File: reader, tracker: tracker}
+		body, bodyAt = r, r
+	}
+	if err := m.putObject(ctx, file, body, bodyAt, contentType, destFile, journal, tracker); err != nil {
 		return err
 	}
 	m.updateFileTransferStatistics(file.size)
 	return nil
 }
 
-func (m *Manager) deleteRemote(ctx context.Context, file *fileInfo, destPath *s3Path) error {
-	destFile := *destPath
-	if strings.HasSuffix(destPath.bucketPrefix, "/") || destPath.bucketPrefix == "" || !file.singleFile {
-		// If source is a single file and destination is not a directory, use destination URL as is.
-		destFile.bucketPrefix = path.Join(destPath.bucketPrefix, file.name)
+// uploadContentType returns the content type of the uploaded object,
+// which is specified by WithContentType or detected unless WithoutGuessMimeType is set.
+func (m *Manager) uploadContentType(detect func() (*mimetype.MIME, error)) (*string, error) {
+	switch {
+	case m.contentType != nil:
+		return m.contentType, nil
+	case m.guessMime:
+		mime, err := detect()
+		if err != nil {
+			return nil, err
+		}
+		s := mime.String()
+		return &s, nil
 	}
+	return nil, nil
+}
 
-	logf("delete: %s", destFile.String())
-
-	if m.dryrun {
-		return nil
+// putObject uploads the contents of the file, compressed and encrypted if configured.
+// If bodyAt is not nil, the parts of the multipart upload are recorded in the journal
+// and can be uploaded again from it.
+func (m *Manager) putObject(ctx context.Context, file *fileInfo, body io.Reader, bodyAt io.ReaderAt, contentType *string, destFile *s3Path, journal *syncJournal, tracker *progressTracker) error {
+	attrs := m.objectAttributes(file.name)
+	if m.keyProvider != nil || m.compression != CompressionNone || m.preservesAttributes() {
+		if attrs.Metadata == nil {
+			attrs.Metadata = make(map[string]string)
+		}
+		setFileMetadata(attrs.Metadata, file)
+	}
+	if m.compression != CompressionNone {
+		compressed := compress(body, m.compression)
+		defer compressed.Close()
+		body = compressed
+		attrs.Metadata[metaCompression] = string(m.compression)
+		if m.keyProvider == nil {
+			attrs.ContentEncoding = aws.String(string(m.compression))
+		}
+	}
+	if m.keyProvider != nil {
+		var err error
+		if body, err = m.encrypt(ctx, body, attrs.Metadata); err != nil {
+			return err
+		}
 	}
+	input := &s3.PutObjectInput{
+		Bucket:             &destFile.bucket,
+		Key:                &destFile.bucketPrefix,
+		ACL:                m.acl,
+		Body:               body,
+		ContentType:        contentType,
+		CacheControl:       attrs.CacheControl,
+		ContentDisposition: attrs.ContentDisposition,
+		ContentEncoding:    attrs.ContentEncoding,
+		ContentLanguage:    attrs.ContentLanguage,
+		Expires:            attrs.Expires,
+		Metadata:           attrs.Metadata,
+		StorageClass:       attrs.StorageClass,
+		Tagging:            attrs.tagging(),
+	}
+	m.sse.putObject(input)
+	if journal != nil && bodyAt != nil && m.compression == CompressionNone && m.keyProvider == nil && file.size > m.uploadPartSize(file.size) {
+		// The parts can be uploaded again only if the body is read from the file as is.
+		return m.uploadMultipart(ctx, file, bodyAt, input, journal, tracker)
+	}
+	_, err := manager.NewUploader(m.s3, m.uploaderOpts...).Upload(ctx, input)
+	return err
+}
+
+func (m *Manager) deleteRemote(ctx context.Context, file *fileInfo, destPath *s3Path) error {
+	destFile := remoteTarget(file, destPath)
 
 	_, err := m.s3.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: &destFile.bucket,
@@ -437,61 +816,137 @@ func (m *Manager) deleteRemote(ctx context.Context, file *fileInfo, destPath *s3
 	return nil
 }
 
+// listBufferSize is the number of the listed files buffered ahead of the comparison.
+// It is the page size of ListObjectsV2 so that the next page is requested while
+// the files are compared.
+const listBufferSize = 1000
+
 // listS3Files return a channel which receives the file infos under the given s3Path.
 func (m *Manager) listS3Files(ctx context.Context, path *s3Path) chan *fileInfo {
-	c := make(chan *fileInfo, 50000) // TODO: revisit this buffer size later
+	return m.listPrefixFiles(ctx, path, func(dir *s3Path) chan *fileInfo {
+		jobs := m.listJobs
+		if dir != path {
+			// One of the requests lists the other objects having the prefix.
+			jobs--
+		}
+		c := make(chan *fileInfo, listBufferSize)
 
+		go func() {
+			defer close(c)
+			if jobs > 1 {
+				m.listS3FilesParallel(ctx, c, dir, jobs)
+				return
+			}
+			m.listS3Range(ctx, c, dir, keyRange{})
+		}()
+
+		return c
+	})
+}
+
+// listPrefixFiles returns a channel which receives the file infos of the objects
+// having the prefix of the given s3Path in order of the names.
+// If the prefix doesn't end with a slash, the objects under the directory of the
+// prefix are listed by listDir, and merged with the other objects having the
+// prefix, e.g. "dir-foo" for "dir", which precede or follow the directory in
+// order of the keys.
+func (m *Manager) listPrefixFiles(ctx context.Context, path *s3Path, listDir func(*s3Path) chan *fileInfo) chan *fileInfo {
+	if path.bucketPrefix == "" || strings.HasSuffix(path.bucketPrefix, "/") {
+		return listDir(path)
+	}
+	dir := &s3Path{bucket: path.bucket, bucketPrefix: path.bucketPrefix + "/"}
+	return mergeSortedFiles(ctx, listDir(dir), m.listS3Siblings(ctx, path))
+}
+
+// listS3Siblings returns a channel which receives the file infos of the objects
+// having the prefix of the given s3Path but not placed under the directory of it,
+// in order of the names. The objects under the directory are not listed.
+func (m *Manager) listS3Siblings(ctx context.Context, path *s3Path) chan *fileInfo {
+	dir := path.bucketPrefix + "/"
+	objects := make(chan *fileInfo)
+	go func() {
+		defer close(objects)
+		// The objects preceding the directory, and following it.
+		// No valid key under the directory follows dir+utf8.MaxRune except
+		// the ones starting with it, which are skipped below.
+		m.listS3Range(ctx, objects, path, keyRange{end: dir})
+		m.listS3Range(ctx, objects, path, keyRange{startAfter: dir + string(utf8.MaxRune)})
+	}()
+
+	c := make(chan *fileInfo)
 	go func() {
 		defer close(c)
-		var token *string
-		for {
-			if token = m.listS3FileWithToken(ctx, c, path, token); token == nil {
-				break
+		send := func(fi *fileInfo) bool {
+			select {
+			case c <- fi:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		// The single file named by the base name of the prefix is placed in
+		// order of the names of the others named by the rest of the keys.
+		var single *fileInfo
+		for fi := range objects {
+			switch {
+			case fi.err != nil:
+			case fi.singleFile:
+				single = fi
+				continue
+			case strings.HasPrefix(fi.path, dir):
+				continue
+			case single != nil && single.name < fi.name:
+				if !send(single) {
+					return
+				}
+				single = nil
+			}
+			if !send(fi) {
+				return
 			}
 		}
+		if single != nil {
+			send(single)
+		}
 	}()
-
 	return c
 }
 
-// listS3FileWithToken lists (send to the result channel) the s3 files from the given continuation token.
-func (m *Manager) listS3FileWithToken(ctx context.Context, c chan *fileInfo, path *s3Path, token *string) *string {
-	list, err := m.s3.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+// listS3Range lists (send to the result channel) the s3 files in the key range.
+func (m *Manager) listS3Range(ctx context.Context, c chan *fileInfo, path *s3Path, r keyRange) {
+	var token *string
+	for {
+		if token = m.listS3FileWithToken(ctx, c, path, r, token); token == nil {
+			break
+		}
+	}
+}
+
+// listS3FileWithToken lists (send to the result channel) the s3 files in the key range
+// from the given continuation token.
+func (m *Manager) listS3FileWithToken(ctx context.Context, c chan *fileInfo, path *s3Path, r keyRange, token *string) *string {
+	input := &s3.ListObjectsV2Input{
 		Bucket:            &path.bucket,
 		Prefix:            &path.bucketPrefix,
 		ContinuationToken: token,
-	})
+	}
+	if r.startAfter != "" {
+		input.StartAfter = &r.startAfter
+	}
+	list, err := m.s3.ListObjectsV2(ctx, input)
 	if err != nil {
-		sendErrorInfoToChannel(ctx, c, err)
+		sendErrorInfoToChannel(ctx, c, &ListError{Path: path.String(), Err: err})
 		return nil
 	}
 
 	for _, object := range list.Contents {
-		if strings.HasSuffix(*object.Key, "/") {
-			// Skip directory like object
-			continue
+		if r.end != "" && *object.Key > r.end {
+			// Rest of the objects are listed by the next range.
+			return nil
 		}
-
-		name := strings.TrimPrefix(*object.Key, path.bucketPrefix)
-		name = strings.TrimPrefix(name, "/")
-
-		var fi *fileInfo
-		if name == "" || name == "." {
-			// Single file was specified
-			fi = &fileInfo{
-				name:         filepath.Base(*object.Key),
-				path:         filepath.Dir(*object.Key),
-				size:         *object.Size,
-				lastModified: *object.LastModified,
-				singleFile:   true,
-			}
-		} else {
-			fi = &fileInfo{
-				name:         name,
-				path:         *object.Key,
-				size:         *object.Size,
-				lastModified: *object.LastModified,
-			}
+		fi := m.s3FileInfo(path, object)
+		if fi == nil {
+			continue
 		}
 		select {
 		case c <- fi:
@@ -503,6 +958,45 @@ func (m *Manager) listS3FileWithToken(ctx context.Context, c chan *fileInfo, pat
 	return list.NextContinuationToken
 }
 
+// s3FileInfo returns the info of the listed object under the given s3Path,
+// or nil if the object is not synced.
+func (m *Manager) s3FileInfo(path *s3Path, object types.Object) *fileInfo {
+	if strings.HasSuffix(*object.Key, "/") {
+		// Skip directory like object
+		return nil
+	}
+
+	name := strings.TrimPrefix(*object.Key, path.bucketPrefix)
+	name = strings.TrimPrefix(name, "/")
+
+	var fi *fileInfo
+	if name == "" || name == "." {
+		// Single file was specified
+		fi = &fileInfo{
+			name:         filepath.Base(*object.Key),
+			path:         filepath.Dir(*object.Key),
+			size:         *object.Size,
+			lastModified: *object.LastModified,
+			singleFile:   true,
+		}
+	} else {
+		fi = &fileInfo{
+			name:         name,
+			path:         *object.Key,
+			size:         *object.Size,
+			lastModified: *object.LastModified,
+		}
+	}
+	if m.isExcluded(fi.name) {
+		return nil
+	}
+	fi.etag = strings.Trim(aws.ToString(object.ETag), `"`)
+	fi.checksumAlgorithm = object.ChecksumAlgorithm
+	fi.checksumType = object.ChecksumType
+	fi.storageClass = object.StorageClass
+	return fi
+}
+
 // updateSyncStatistics updates the statistics of the amount of bytes transferred for one file
 func (m *Manager) updateFileTransferStatistics(written int64) {
 	m.statistics.mutex.Lock()
@@ -511,6 +1005,13 @@ func (m *Manager) updateFileTransferStatistics(written int64) {
 	m.statistics.Bytes += written
 }
 
+// incrementRetries increments the counter of the retried file operations.
+func (m *Manager) incrementRetries() {
+	m.statistics.mutex.Lock()
+	defer m.statistics.mutex.Unlock()
+	m.statistics.Retries++
+}
+
 // incrementDeletedFiles increments the counter used to capture the number of remote files deleted during the synchronization process
 func (m *Manager) incrementDeletedFiles() {
 	m.statistics.mutex.Lock()
@@ -520,7 +1021,7 @@ func (m *Manager) incrementDeletedFiles() {
 
 // listLocalFiles returns a channel which receives the infos of the files under the given basePath.
 // basePath have to be absolute path.
-func listLocalFiles(ctx context.Context, basePath string) chan *fileInfo {
+func (m *Manager) listLocalFiles(ctx context.Context, basePath string) chan *fileInfo {
 	c := make(chan *fileInfo)
 
 	basePath = filepath.ToSlash(basePath)
@@ -534,44 +1035,55 @@ func listLocalFiles(ctx context.Context, basePath string) chan *fileInfo {
 			// Returns and closes the channel without sending any.
 			return
 		} else if err != nil {
-			sendErrorInfoToChannel(ctx, c, err)
+			sendErrorInfoToChannel(ctx, c, &ListError{Path: basePath, Err: err})
 			return
 		}
 
 		if !stat.IsDir() {
-			sendFileInfoToChannel(ctx, c, filepath.Dir(basePath), basePath, stat, true)
+			m.sendFileInfoToChannel(ctx, c, filepath.Dir(basePath), basePath, stat, true)
 			return
 		}
 
-		sendFileInfoToChannel(ctx, c, basePath, basePath, stat, false)
-
-		err = filepath.Walk(basePath, func(path string, stat os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			sendFileInfoToChannel(ctx, c, basePath, path, stat, false)
-			return ctx.Err()
-		})
+		m.sendFileInfoToChannel(ctx, c, basePath, basePath, stat, false)
 
+		err = m.walkLocal(ctx, c, basePath, basePath, []os.FileInfo{stat})
 		if err != nil {
-			sendErrorInfoToChannel(ctx, c, err)
+			sendErrorInfoToChannel(ctx, c, &ListError{Path: basePath, Err: err})
 		}
 
 	}()
 	return c
 }
 
-func sendFileInfoToChannel(ctx context.Context, c chan *fileInfo, basePath, path string, stat os.FileInfo, singleFile bool) {
+func (m *Manager) sendFileInfoToChannel(ctx context.Context, c chan *fileInfo, basePath, path string, stat os.FileInfo, singleFile bool) {
 	if stat == nil || stat.IsDir() {
 		return
 	}
 	relPath, _ := filepath.Rel(basePath, path)
+	if isTempFile(path) || m.isExcluded(relPath) {
+		return
+	}
+	if m.keepPartial && isPartialFile(relPath) {
+		m.log(LogEvent{
+			Level:   slog.LevelInfo,
+			Message: "skipping partial file of the resumable download: " + path,
+			Path:    relPath,
+		}, true)
+		return
+	}
 	fi := &fileInfo{
 		name:         relPath,
 		path:         path,
 		size:         stat.Size(),
 		lastModified: stat.ModTime(),
 		singleFile:   singleFile,
+		symlink:      stat.Mode()&os.ModeSymlink != 0,
+	}
+	if m.preserveMode && !fi.symlink {
+		fi.mode = stat.Mode().Perm()
+	}
+	if m.preserveOwner {
+		fi.owner = fileOwnerOf(stat)
 	}
 	select {
 	case c <- fi:
@@ -591,35 +1103,76 @@ func sendErrorInfoToChannel(ctx context.Context, c chan *fileInfo, err error) {
 
 // filterFilesForSync filters the source files from the given destination files, and returns
 // another channel which includes the files necessary to be synced.
-func filterFilesForSync(sourceFileChan, destFileChan chan *fileInfo, del bool) chan *fileOp {
+// compare is called for the files existing on both sides.
+//
+// Both channels have to receive the files in lexicographical order of the slash
+// separated names, as ListObjectsV2 returns the objects, so that the files are
+// compared by a merge join without loading them into memory.
+func filterFilesForSync(ctx context.Context, sourceFileChan, destFileChan chan *fileInfo, del bool, compare compareFunc) chan *fileOp {
 	c := make(chan *fileOp)
 
-	destFiles, err := fileInfoChanToMap(destFileChan)
-
 	go func() {
 		defer close(c)
-		if err != nil {
-			c <- &fileOp{fileInfo: &fileInfo{err: err}}
-			return
-		}
-		for sourceInfo := range sourceFileChan {
-			destInfo, ok := destFiles[sourceInfo.name]
-			// source is necessary to sync if
-			// 1. The dest doesn't exist
-			// 2. The dest doesn't have the same size as the source
-			// 3. The dest is older than the source
-			if !ok || sourceInfo.size != destInfo.size || sourceInfo.lastModified.After(destInfo.lastModified) {
-				c <- &fileOp{fileInfo: sourceInfo}
-			}
-			if ok {
-				destInfo.existsInSource = true
+		send := func(op *fileOp) bool {
+			select {
+			case c <- op:
+				return true
+			case <-ctx.Done():
+				return false
 			}
 		}
-		if del {
-			for _, destInfo := range destFiles {
-				if !destInfo.existsInSource {
-					// The source doesn't exist
-					c <- &fileOp{fileInfo: destInfo, op: opDelete}
+		source := &sortedFiles{c: sourceFileChan}
+		dest := &sortedFiles{c: destFileChan}
+		var sourceFailed bool
+		for {
+			sourceInfo, destInfo := source.peek(), dest.peek()
+			switch {
+			case destInfo != nil && destInfo.err != nil:
+				send(&fileOp{fileInfo: destInfo})
+				return
+			case sourceInfo != nil && sourceInfo.err != nil:
+				// Files missing on the partially listed source must not be deleted.
+				sourceFailed = true
+				if !send(&fileOp{fileInfo: sourceInfo}) {
+					return
+				}
+				source.pop()
+			case sourceInfo == nil && destInfo == nil:
+				return
+			case destInfo == nil || (sourceInfo != nil && source.key < dest.key):
+				// The dest doesn't exist
+				if !send(&fileOp{fileInfo: sourceInfo, reason: ReasonMissing}) {
+					return
+				}
+				source.pop()
+			case sourceInfo == nil || dest.key < source.key:
+				// The source doesn't exist
+				if del && !sourceFailed {
+					if !send(&fileOp{fileInfo: destInfo, op: opDelete, reason: ReasonNotInSource}) {
+						return
+					}
+				}
+				dest.pop()
+			default:
+				// The dest is necessary to be updated if it is not up to date
+				// (e.g. has different size or is older than the source)
+				source.pop()
+				dest.pop()
+				reason, err := compare(sourceInfo, destInfo)
+				if err != nil {
+					failed := *sourceInfo
+					failed.err = err
+					if !send(&fileOp{fileInfo: &failed}) {
+						return
+					}
+					continue
+				}
+				op := &fileOp{fileInfo: sourceInfo, reason: reason, dest: destInfo}
+				if reason == "" {
+					op = &fileOp{fileInfo: sourceInfo, op: opSkip, dest: destInfo}
+				}
+				if !send(op) {
+					return
 				}
 			}
 		}
@@ -628,16 +1181,72 @@ func filterFilesForSync(sourceFileChan, destFileChan chan *fileInfo, del bool) c
 	return c
 }
 
-// fileInfoChanToMap accumulates the fileInfos from the given channel and returns a map.
-// It retruns an error if the channel contains an error.
-func fileInfoChanToMap(files chan *fileInfo) (map[string]*fileInfo, error) {
-	result := make(map[string]*fileInfo)
+// sortedFiles reads the files received in lexicographical order of the names.
+type sortedFiles struct {
+	c    chan *fileInfo
+	next *fileInfo
+	// key is the slash separated name of the next file.
+	key  string
+	done bool
+}
 
-	for file := range files {
-		if file.err != nil {
-			return nil, file.err
+// peek returns the next file without consuming it, or nil at the end of the files.
+// The error is returned as a file if the files are not sorted.
+func (s *sortedFiles) peek() *fileInfo {
+	if s.next != nil || s.done {
+		return s.next
+	}
+	file, ok := <-s.c
+	if !ok {
+		s.done = true
+		return nil
+	}
+	if file.err == nil {
+		key := filepath.ToSlash(file.name)
+		if s.key != "" && key <= s.key {
+			file = &fileInfo{err: fmt.Errorf("files are not listed in order: %q after %q", key, s.key)}
 		}
-		result[file.name] = file
+		s.key = key
 	}
-	return result, nil
+	s.next = file
+	return file
+}
+
+// pop consumes the next file.
+func (s *sortedFiles) pop() {
+	s.next = nil
+}
+
+// mergeSortedFiles returns a channel which receives the files of the both
+// channels in lexicographical order of the names.
+// Each channel has to receive the files in order.
+func mergeSortedFiles(ctx context.Context, c1, c2 chan *fileInfo) chan *fileInfo {
+	c := make(chan *fileInfo)
+
+	go func() {
+		defer close(c)
+		s1 := &sortedFiles{c: c1}
+		s2 := &sortedFiles{c: c2}
+		for {
+			f1, f2 := s1.peek(), s2.peek()
+			var fi *fileInfo
+			switch {
+			case f1 == nil && f2 == nil:
+				return
+			case f2 == nil, f1 != nil && (f1.err != nil || (f2.err == nil && s1.key < s2.key)):
+				fi = f1
+				s1.pop()
+			default:
+				fi = f2
+				s2.pop()
+			}
+			select {
+			case c <- fi:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return c
 }
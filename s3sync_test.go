@@ -16,12 +16,14 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
 	"sort"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -29,12 +31,105 @@ import (
 
 const dummyFilename = "README.md"
 
-func TestS3syncNotImplemented(t *testing.T) {
-	m := New(getSession())
+func TestLocalToLocal(t *testing.T) {
+	t0 := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
 
-	if err := m.Sync(context.Background(), "foo", "bar"); err == nil {
-		t.Fatal("local to local sync is not supported")
+	setup := func(t *testing.T) (string, string) {
+		source := t.TempDir()
+		dest := t.TempDir()
+		for _, file := range []string{
+			filepath.Join(source, "test1"),
+			filepath.Join(source, "foo", "test2"),
+			filepath.Join(dest, "dest_only"),
+		} {
+			if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+				t.Fatal("Failed to mkdir", err)
+			}
+			if err := os.WriteFile(file, make([]byte, 10), 0644); err != nil {
+				t.Fatal("Failed to write", err)
+			}
+			if err := os.Chtimes(file, t0, t0); err != nil {
+				t.Fatal("Failed to chtimes", err)
+			}
+		}
+		return source, dest
 	}
+
+	t.Run("Copy", func(t *testing.T) {
+		source, dest := setup(t)
+		m := New(getSession(), WithDelete())
+		if err := m.Sync(context.Background(), source, dest); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		for _, file := range []string{"test1", "foo/test2"} {
+			filename := filepath.Join(dest, file)
+			fileHasSize(t, filename, 10)
+			stat, err := os.Stat(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !stat.ModTime().Equal(t0) {
+				t.Errorf("Modification time of %s should be preserved, got %v", file, stat.ModTime())
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dest, "dest_only")); !os.IsNotExist(err) {
+			t.Error("dest_only should be deleted")
+		}
+		stats := m.GetStatistics()
+		if stats.Files != 2 || stats.Bytes != 20 || stats.DeletedFiles != 1 {
+			t.Errorf("Unexpected statistics: files %d, bytes %d, deleted %d", stats.Files, stats.Bytes, stats.DeletedFiles)
+		}
+
+		// Second sync doesn't copy anything.
+		m = New(getSession())
+		if err := m.Sync(context.Background(), source, dest); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if n := m.GetStatistics().Files; n != 0 {
+			t.Errorf("Up to date files should not be copied, but %d files are copied", n)
+		}
+	})
+	t.Run("SingleFile", func(t *testing.T) {
+		source, dest := setup(t)
+		if err := New(getSession()).Sync(context.Background(), filepath.Join(source, "foo", "test2"), filepath.Join(dest, "bar")); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		fileHasSize(t, filepath.Join(dest, "bar"), 10)
+	})
+	t.Run("Checksum", func(t *testing.T) {
+		source, dest := setup(t)
+		if err := os.WriteFile(filepath.Join(dest, "test1"), []byte("0123456789"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		m := New(getSession(), WithCompareMode(CompareChecksum))
+		if err := m.Sync(context.Background(), source, dest); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		data, err := os.ReadFile(filepath.Join(dest, "test1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, make([]byte, 10)) {
+			t.Error("File having different contents should be copied")
+		}
+	})
+	t.Run("DryRun", func(t *testing.T) {
+		source, dest := setup(t)
+		m := New(getSession(), WithDelete(), WithDryRun())
+		if err := m.Sync(context.Background(), source, dest); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if _, err := os.Stat(filepath.Join(dest, "test1")); !os.IsNotExist(err) {
+			t.Error("test1 should not be copied on dry-run")
+		}
+		if _, err := os.Stat(filepath.Join(dest, "dest_only")); err != nil {
+			t.Error("dest_only should not be deleted on dry-run")
+		}
+		stats := m.GetStatistics()
+		if !reflect.DeepEqual(&stats, &SyncStatistics{}) {
+			t.Error("Statistics must not change on a dry-run")
+		}
+	})
 }
 
 func TestS3sync(t *testing.T) {
@@ -175,6 +270,28 @@ func TestS3sync(t *testing.T) {
 		}
 	})
 
+	t.Run("S3ToS3MultipartCopy", func(t *testing.T) {
+		m := New(getSession(), WithCopyThreshold(1))
+		if err := m.Sync(context.Background(), "s3://s3-source", "s3://s3-destination-multipart"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+
+		objs := listObjectsSorted(t, "s3-destination-multipart")
+		if n := len(objs); n != 3 {
+			t.Fatalf("Number of the files should be 3 (result: %v)", objs)
+		}
+		for _, obj := range objs {
+			if obj.size != dummyFileSize {
+				t.Errorf("Object size should be %d, actual %d", dummyFileSize, obj.size)
+			}
+		}
+		if objs[0].path != "README.md" ||
+			objs[1].path != "bar/baz/README.md" ||
+			objs[2].path != "foo/README.md" {
+			t.Error("Unexpected keys", objs)
+		}
+	})
+
 	t.Run("Upload", func(t *testing.T) {
 		temp, err := os.MkdirTemp("", "s3synctest")
 		defer os.RemoveAll(temp)
@@ -517,6 +634,37 @@ func TestDelete(t *testing.T) {
 		}
 
 	})
+	t.Run("DeleteS3ToS3", func(t *testing.T) {
+		m := New(getSession(), WithDelete())
+		if err := m.Sync(context.Background(), "s3://s3-source", "s3://s3-destination-delete"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+
+		objs := listObjectsSorted(t, "s3-destination-delete")
+		if n := len(objs); n != 3 {
+			t.Fatalf("Number of the files should be 3 (result: %v)", objs)
+		}
+		for _, obj := range objs {
+			if obj.size != dummyFileSize {
+				t.Errorf("Object size should be %d, actual %d", dummyFileSize, obj.size)
+			}
+		}
+		if objs[0].path != "README.md" ||
+			objs[1].path != "bar/baz/README.md" ||
+			objs[2].path != "foo/README.md" {
+			t.Error("Unexpected keys", objs)
+		}
+		stats := m.GetStatistics()
+		if stats.Files != 3 {
+			t.Errorf("Expected files copied: %d, but found %d", 3, stats.Files)
+		}
+		if stats.Bytes != int64(stats.Files)*int64(dummyFileSize) {
+			t.Errorf("Expected bytes copied: %d, but found %d", int64(stats.Files)*int64(dummyFileSize), stats.Bytes)
+		}
+		if stats.DeletedFiles != 1 {
+			t.Errorf("Expected deleted files: %d, but found %d", 1, stats.DeletedFiles)
+		}
+	})
 }
 
 func TestDryRun(t *testing.T) {
@@ -611,6 +759,220 @@ func TestDryRun(t *testing.T) {
 			t.Error("Statistics must not change on a dry-run")
 		}
 	})
+	t.Run("S3ToS3", func(t *testing.T) {
+		m := New(getSession(), WithDelete(), WithDryRun())
+		if err := m.Sync(context.Background(), "s3://s3-source", "s3://s3-destination-dryrun"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+
+		objs := listObjectsSorted(t, "s3-destination-dryrun")
+		if n := len(objs); n != 1 {
+			t.Fatalf("Number of the files should be 1 (result: %v)", objs)
+		}
+		if objs[0].path != "dest_only_file" {
+			t.Error("Unexpected key", objs[0].path)
+		}
+		stats := m.GetStatistics()
+		if !reflect.DeepEqual(&stats, &SyncStatistics{}) {
+			t.Error("Statistics must not change on a dry-run")
+		}
+	})
+}
+
+func TestPlan(t *testing.T) {
+	data, err := os.ReadFile(dummyFilename)
+	if err != nil {
+		t.Fatal("Failed to read", dummyFilename)
+	}
+	dummyFileSize := len(data)
+
+	temp, err := os.MkdirTemp("", "s3synctest")
+	defer os.RemoveAll(temp)
+	if err != nil {
+		t.Fatal("Failed to create temp dir")
+	}
+
+	if err := os.MkdirAll(filepath.Join(temp, "foo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(temp, "foo", "README.md"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(temp, "README.md"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(temp, "local_only"), []byte("test"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := New(getSession(), WithDelete())
+	plan, err := m.Plan(context.Background(), "s3://s3-source", temp)
+	if err != nil {
+		t.Fatal("Plan should be successful", err)
+	}
+	ops := plan.Operations
+	sort.Slice(ops, func(i, j int) bool { return ops[i].Dest < ops[j].Dest })
+
+	type op struct {
+		typ          OperationType
+		source, dest string
+		reason       Reason
+	}
+	expected := []op{
+		{OperationDownload, "s3://s3-source/README.md", filepath.Join(temp, "README.md"), ReasonSizeDiffers},
+		{OperationDownload, "s3://s3-source/bar/baz/README.md", filepath.Join(temp, "bar/baz/README.md"), ReasonMissing},
+		{OperationDelete, "", filepath.Join(temp, "local_only"), ReasonNotInSource},
+	}
+	var actual []op
+	for _, o := range ops {
+		actual = append(actual, op{o.Type, o.Source, o.Dest, o.Reason})
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected operations: %v, actual: %v", expected, actual)
+	}
+	if ops[1].Size != int64(dummyFileSize) {
+		t.Errorf("Expected size: %d, actual: %d", dummyFileSize, ops[1].Size)
+	}
+	if _, err := os.Stat(filepath.Join(temp, "bar")); !os.IsNotExist(err) {
+		t.Error("Plan must not download the files")
+	}
+	if _, err := os.Stat(filepath.Join(temp, "local_only")); err != nil {
+		t.Error("Plan must not delete the files")
+	}
+
+	if err := m.ExecutePlan(context.Background(), plan); err != nil {
+		t.Fatal("ExecutePlan should be successful", err)
+	}
+	fileHasSize(t, filepath.Join(temp, "README.md"), dummyFileSize)
+	fileHasSize(t, filepath.Join(temp, "bar/baz/README.md"), dummyFileSize)
+	if _, err := os.Stat(filepath.Join(temp, "local_only")); !os.IsNotExist(err) {
+		t.Error("local_only must be deleted")
+	}
+	stats := m.GetStatistics()
+	if stats.Files != 2 || stats.DeletedFiles != 1 {
+		t.Errorf("Unexpected statistics: files %d, deleted %d", stats.Files, stats.DeletedFiles)
+	}
+}
+
+type recordingObserver struct {
+	mu       sync.Mutex
+	events   map[string][]string
+	progress map[string]int64
+	reports  map[string][]int64
+}
+
+func (o *recordingObserver) record(op Operation, event string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.events == nil {
+		o.events = make(map[string][]string)
+	}
+	o.events[op.Dest] = append(o.events[op.Dest], event)
+}
+
+func (o *recordingObserver) OnPlanned(op Operation)  { o.record(op, "planned") }
+func (o *recordingObserver) OnStart(op Operation)    { o.record(op, "start") }
+func (o *recordingObserver) OnComplete(op Operation) { o.record(op, "complete") }
+func (o *recordingObserver) OnSkip(op Operation)     { o.record(op, "skip") }
+func (o *recordingObserver) OnError(op Operation, err error) {
+	o.record(op, "error")
+}
+
+func (o *recordingObserver) OnProgress(op Operation, bytesDone int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.progress == nil {
+		o.progress = make(map[string]int64)
+		o.reports = make(map[string][]int64)
+	}
+	o.progress[op.Dest] = bytesDone
+	o.reports[op.Dest] = append(o.reports[op.Dest], bytesDone)
+}
+
+func TestObserver(t *testing.T) {
+	data, err := os.ReadFile(dummyFilename)
+	if err != nil {
+		t.Fatal("Failed to read", dummyFilename)
+	}
+	dummyFileSize := len(data)
+
+	temp, err := os.MkdirTemp("", "s3synctest")
+	defer os.RemoveAll(temp)
+	if err != nil {
+		t.Fatal("Failed to create temp dir")
+	}
+	if err := os.WriteFile(filepath.Join(temp, "README.md"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	o := &recordingObserver{}
+	if err := New(getSession(), WithObserver(o)).Sync(context.Background(), "s3://s3-source", temp); err != nil {
+		t.Fatal("Sync should be successful", err)
+	}
+
+	expected := map[string][]string{
+		filepath.Join(temp, "README.md"):         {"skip"},
+		filepath.Join(temp, "foo/README.md"):     {"planned", "start", "complete"},
+		filepath.Join(temp, "bar/baz/README.md"): {"planned", "start", "complete"},
+	}
+	if !reflect.DeepEqual(expected, o.events) {
+		t.Errorf("Expected events: %v, actual: %v", expected, o.events)
+	}
+	expectedProgress := map[string]int64{
+		filepath.Join(temp, "foo/README.md"):     int64(dummyFileSize),
+		filepath.Join(temp, "bar/baz/README.md"): int64(dummyFileSize),
+	}
+	if !reflect.DeepEqual(expectedProgress, o.progress) {
+		t.Errorf("Expected progress: %v, actual: %v", expectedProgress, o.progress)
+	}
+}
+
+func TestSyncFailure(t *testing.T) {
+	t.Run("FileError", func(t *testing.T) {
+		temp, err := os.MkdirTemp("", "s3synctest")
+		defer os.RemoveAll(temp)
+		if err != nil {
+			t.Fatal("Failed to create temp dir")
+		}
+		// Regular file prevents creating foo/ directory.
+		if err := os.WriteFile(filepath.Join(temp, "foo"), []byte("test"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		err = New(getSession()).Sync(context.Background(), "s3://s3-source", temp)
+		var syncErr *SyncError
+		if !errors.As(err, &syncErr) {
+			t.Fatalf("SyncError is expected, got %v", err)
+		}
+		fe := syncErr.FileErrors()
+		if len(fe) != 1 || len(syncErr.ListErrors()) != 0 {
+			t.Fatalf("Only one FileError is expected, got %v", syncErr.Errors)
+		}
+		if fe[0].Op != OperationDownload ||
+			fe[0].Source != "s3://s3-source/foo/README.md" ||
+			fe[0].Dest != filepath.Join(temp, "foo/README.md") {
+			t.Errorf("Unexpected FileError: %+v", fe[0])
+		}
+		if _, err := os.Stat(filepath.Join(temp, "bar/baz/README.md")); err != nil {
+			t.Error("Other files should be synced", err)
+		}
+	})
+	t.Run("ListError", func(t *testing.T) {
+		temp, err := os.MkdirTemp("", "s3synctest")
+		defer os.RemoveAll(temp)
+		if err != nil {
+			t.Fatal("Failed to create temp dir")
+		}
+
+		err = New(getSession()).Sync(context.Background(), "s3://s3-nonexistent-bucket/foo", temp)
+		var le *ListError
+		if !errors.As(err, &le) {
+			t.Fatalf("ListError is expected, got %v", err)
+		}
+		if le.Path != "s3://s3-nonexistent-bucket/foo" {
+			t.Errorf("Unexpected path: %s", le.Path)
+		}
+	})
 }
 
 func TestPartialS3sync(t *testing.T) {
@@ -730,6 +1092,65 @@ func TestPartialS3sync(t *testing.T) {
 	})
 }
 
+func TestCompareChecksum(t *testing.T) {
+	data, err := os.ReadFile(dummyFilename)
+	if err != nil {
+		t.Fatal("Failed to read", dummyFilename)
+	}
+
+	temp, err := os.MkdirTemp("", "s3synctest")
+	defer os.RemoveAll(temp)
+
+	if err != nil {
+		t.Fatal("Failed to create temp dir")
+	}
+
+	filename := filepath.Join(temp, dummyFilename)
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal("Failed to write", err)
+	}
+
+	if err := New(getSession()).Sync(context.Background(), temp, "s3://example-bucket-checksum"); err != nil {
+		t.Fatal("Sync should be successful", err)
+	}
+
+	// Touch the file without changing the contents.
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filename, future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("SameContents", func(t *testing.T) {
+		m := New(getSession(), WithCompareMode(CompareChecksum))
+		if err := m.Sync(context.Background(), temp, "s3://example-bucket-checksum"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if n := m.GetStatistics().Files; n != 0 {
+			t.Errorf("Expected files uploaded: %d, but found %d", 0, n)
+		}
+	})
+
+	t.Run("DifferentContents", func(t *testing.T) {
+		// Same size, different contents
+		modified := make([]byte, len(data))
+		if err := os.WriteFile(filename, modified, 0644); err != nil {
+			t.Fatal("Failed to write", err)
+		}
+		past := time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+		if err := os.Chtimes(filename, past, past); err != nil {
+			t.Fatal(err)
+		}
+
+		m := New(getSession(), WithCompareMode(CompareChecksum))
+		if err := m.Sync(context.Background(), temp, "s3://example-bucket-checksum"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if n := m.GetStatistics().Files; n != 1 {
+			t.Errorf("Expected files uploaded: %d, but found %d", 1, n)
+		}
+	})
+}
+
 func TestListLocalFiles(t *testing.T) {
 	temp, err := os.MkdirTemp("", "s3synctest")
 	defer os.RemoveAll(temp)
@@ -758,6 +1179,8 @@ func TestListLocalFiles(t *testing.T) {
 		}
 	}
 
+	m := New(getSession())
+
 	collectFilePaths := func(ch chan *fileInfo) []string {
 		list := []string{}
 		for f := range ch {
@@ -768,7 +1191,7 @@ func TestListLocalFiles(t *testing.T) {
 	}
 
 	t.Run("Root", func(t *testing.T) {
-		paths := collectFilePaths(listLocalFiles(context.Background(), temp))
+		paths := collectFilePaths(m.listLocalFiles(context.Background(), temp))
 		expected := []string{
 			filepath.Join(temp, "bar", "baz", "test3"),
 			filepath.Join(temp, "foo", "test2"),
@@ -780,7 +1203,7 @@ func TestListLocalFiles(t *testing.T) {
 	})
 
 	t.Run("EmptyDir", func(t *testing.T) {
-		paths := collectFilePaths(listLocalFiles(context.Background(), filepath.Join(temp, "empty")))
+		paths := collectFilePaths(m.listLocalFiles(context.Background(), filepath.Join(temp, "empty")))
 		expected := []string{}
 		if !reflect.DeepEqual(expected, paths) {
 			t.Errorf("Local file list is expected to be %v, got %v", expected, paths)
@@ -788,7 +1211,7 @@ func TestListLocalFiles(t *testing.T) {
 	})
 
 	t.Run("File", func(t *testing.T) {
-		paths := collectFilePaths(listLocalFiles(context.Background(), filepath.Join(temp, "test1")))
+		paths := collectFilePaths(m.listLocalFiles(context.Background(), filepath.Join(temp, "test1")))
 		expected := []string{
 			filepath.Join(temp, "test1"),
 		}
@@ -798,7 +1221,7 @@ func TestListLocalFiles(t *testing.T) {
 	})
 
 	t.Run("Dir", func(t *testing.T) {
-		paths := collectFilePaths(listLocalFiles(context.Background(), filepath.Join(temp, "foo")))
+		paths := collectFilePaths(m.listLocalFiles(context.Background(), filepath.Join(temp, "foo")))
 		expected := []string{
 			filepath.Join(temp, "foo", "test2"),
 		}
@@ -808,7 +1231,7 @@ func TestListLocalFiles(t *testing.T) {
 	})
 
 	t.Run("Dir2", func(t *testing.T) {
-		paths := collectFilePaths(listLocalFiles(context.Background(), filepath.Join(temp, "bar")))
+		paths := collectFilePaths(m.listLocalFiles(context.Background(), filepath.Join(temp, "bar")))
 		expected := []string{
 			filepath.Join(temp, "bar", "baz", "test3"),
 		}
@@ -816,6 +1239,137 @@ func TestListLocalFiles(t *testing.T) {
 			t.Errorf("Local file list is expected to be %v, got %v", expected, paths)
 		}
 	})
+
+	t.Run("Exclude", func(t *testing.T) {
+		m := New(getSession(), WithExclude("bar/*", "test1"))
+		paths := collectFilePaths(m.listLocalFiles(context.Background(), temp))
+		expected := []string{
+			filepath.Join(temp, "foo", "test2"),
+		}
+		if !reflect.DeepEqual(expected, paths) {
+			t.Errorf("Local file list is expected to be %v, got %v", expected, paths)
+		}
+	})
+
+	t.Run("ExcludeAndInclude", func(t *testing.T) {
+		m := New(getSession(), WithExclude("*"), WithInclude("*/test[23]"))
+		paths := collectFilePaths(m.listLocalFiles(context.Background(), temp))
+		expected := []string{
+			filepath.Join(temp, "bar", "baz", "test3"),
+			filepath.Join(temp, "foo", "test2"),
+		}
+		if !reflect.DeepEqual(expected, paths) {
+			t.Errorf("Local file list is expected to be %v, got %v", expected, paths)
+		}
+	})
+
+	t.Run("Order", func(t *testing.T) {
+		temp := t.TempDir()
+		for _, name := range []string{"a/b", "a-b", "a.b", "a0", "b/a/c", "b/a-c"} {
+			file := filepath.Join(temp, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+				t.Fatal("Failed to mkdir", err)
+			}
+			if err := os.WriteFile(file, nil, 0644); err != nil {
+				t.Fatal("Failed to write", err)
+			}
+		}
+		var names []string
+		for f := range m.listLocalFiles(context.Background(), temp) {
+			names = append(names, filepath.ToSlash(f.name))
+		}
+		// Same order as the S3 keys.
+		expected := []string{"a-b", "a.b", "a/b", "a0", "b/a-c", "b/a/c"}
+		if !reflect.DeepEqual(expected, names) {
+			t.Errorf("Local file list is expected to be %v, got %v", expected, names)
+		}
+	})
+}
+
+func TestFilterFilesForSync(t *testing.T) {
+	now := time.Now()
+	files := func(names ...string) chan *fileInfo {
+		c := make(chan *fileInfo)
+		go func() {
+			defer close(c)
+			for _, name := range names {
+				if name == "error" {
+					c <- &fileInfo{err: errors.New("list failed")}
+					continue
+				}
+				c <- &fileInfo{name: name, size: 1, lastModified: now}
+			}
+		}()
+		return c
+	}
+	collect := func(c chan *fileOp) []string {
+		var ops []string
+		for op := range c {
+			switch {
+			case op.err != nil:
+				ops = append(ops, "error")
+			case op.op == opDelete:
+				ops = append(ops, "delete "+op.name)
+			case op.op == opSkip:
+				ops = append(ops, "skip "+op.name)
+			default:
+				ops = append(ops, "sync "+op.name)
+			}
+		}
+		return ops
+	}
+
+	testCases := map[string]struct {
+		source, dest []string
+		expected     []string
+	}{
+		"Merge": {
+			source:   []string{"a", "b/c", "d"},
+			dest:     []string{"0", "b/c", "c", "e"},
+			expected: []string{"delete 0", "sync a", "skip b/c", "delete c", "sync d", "delete e"},
+		},
+		"SourceError": {
+			source:   []string{"a", "error"},
+			dest:     []string{"a", "b"},
+			expected: []string{"skip a", "error"},
+		},
+		"DestError": {
+			source:   []string{"a"},
+			dest:     []string{"error"},
+			expected: []string{"error"},
+		},
+		"NotSorted": {
+			source:   []string{"a"},
+			dest:     []string{"b", "a"},
+			expected: []string{"sync a", "delete b", "error"},
+		},
+	}
+	for name, tt := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ops := collect(filterFilesForSync(context.Background(), files(tt.source...), files(tt.dest...), true, compareBySizeAndModTime))
+			if !reflect.DeepEqual(tt.expected, ops) {
+				t.Errorf("Expected %v, got %v", tt.expected, ops)
+			}
+		})
+	}
+	t.Run("Cancel", func(t *testing.T) {
+		names := make([]string, 1000)
+		for i := range names {
+			names[i] = fmt.Sprintf("%04d", i)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		ops := filterFilesForSync(ctx, files(names...), files(), true, compareBySizeAndModTime)
+		<-ops
+		cancel()
+		// The channel is closed without sending all operations.
+		n := 1
+		for range ops {
+			n++
+		}
+		if n == len(names) {
+			t.Error("Filtering should be stopped on cancellation")
+		}
+	})
 }
 
 func TestS3sync_GuessMime(t *testing.T) {
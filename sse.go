@@ -0,0 +1,164 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ServerSideEncryption configures the server-side encryption of the uploaded and copied objects.
+type ServerSideEncryption struct {
+	// Type is the encryption algorithm of SSE-S3 (AES256) or SSE-KMS (aws:kms, aws:kms:dsse).
+	// It must be empty if CustomerKey is set.
+	Type types.ServerSideEncryption
+	// KMSKeyID is the ID of the KMS key used by SSE-KMS.
+	// The AWS managed key is used if empty.
+	KMSKeyID string
+	// KMSEncryptionContext is the additional encryption context of SSE-KMS.
+	KMSEncryptionContext map[string]string
+	// BucketKeyEnabled enables S3 Bucket Key of SSE-KMS.
+	BucketKeyEnabled bool
+	// CustomerKey is the 256-bit key of SSE-C.
+	// The key is also used to read the source objects, so that all objects
+	// on the source and the destination must be encrypted by the same key.
+	CustomerKey []byte
+}
+
+// sseParams is the request parameters of the server-side encryption.
+type sseParams struct {
+	sse               types.ServerSideEncryption
+	kmsKeyID          *string
+	kmsContext        *string
+	bucketKeyEnabled  *bool
+	customerAlgorithm *string
+	customerKey       *string
+	customerKeyMD5    *string
+}
+
+func newSSEParams(e ServerSideEncryption) *sseParams {
+	p := &sseParams{sse: e.Type}
+	if e.KMSKeyID != "" {
+		p.kmsKeyID = &e.KMSKeyID
+	}
+	if len(e.KMSEncryptionContext) > 0 {
+		// Marshaling map[string]string never fails.
+		b, _ := json.Marshal(e.KMSEncryptionContext)
+		p.kmsContext = aws.String(base64.StdEncoding.EncodeToString(b))
+	}
+	if e.BucketKeyEnabled {
+		p.bucketKeyEnabled = aws.Bool(true)
+	}
+	if len(e.CustomerKey) > 0 {
+		sum := md5.Sum(e.CustomerKey)
+		p.customerAlgorithm = aws.String("AES256")
+		p.customerKey = aws.String(base64.StdEncoding.EncodeToString(e.CustomerKey))
+		p.customerKeyMD5 = aws.String(base64.StdEncoding.EncodeToString(sum[:]))
+	}
+	return p
+}
+
+func (p *sseParams) putObject(in *s3.PutObjectInput) {
+	if p == nil {
+		return
+	}
+	in.ServerSideEncryption = p.sse
+	in.SSEKMSKeyId = p.kmsKeyID
+	in.SSEKMSEncryptionContext = p.kmsContext
+	in.BucketKeyEnabled = p.bucketKeyEnabled
+	in.SSECustomerAlgorithm = p.customerAlgorithm
+	in.SSECustomerKey = p.customerKey
+	in.SSECustomerKeyMD5 = p.customerKeyMD5
+}
+
+func (p *sseParams) copyObject(in *s3.CopyObjectInput) {
+	if p == nil {
+		return
+	}
+	in.ServerSideEncryption = p.sse
+	in.SSEKMSKeyId = p.kmsKeyID
+	in.SSEKMSEncryptionContext = p.kmsContext
+	in.BucketKeyEnabled = p.bucketKeyEnabled
+	in.SSECustomerAlgorithm = p.customerAlgorithm
+	in.SSECustomerKey = p.customerKey
+	in.SSECustomerKeyMD5 = p.customerKeyMD5
+	in.CopySourceSSECustomerAlgorithm = p.customerAlgorithm
+	in.CopySourceSSECustomerKey = p.customerKey
+	in.CopySourceSSECustomerKeyMD5 = p.customerKeyMD5
+}
+
+func (p *sseParams) createMultipartUpload(in *s3.CreateMultipartUploadInput) {
+	if p == nil {
+		return
+	}
+	in.ServerSideEncryption = p.sse
+	in.SSEKMSKeyId = p.kmsKeyID
+	in.SSEKMSEncryptionContext = p.kmsContext
+	in.BucketKeyEnabled = p.bucketKeyEnabled
+	in.SSECustomerAlgorithm = p.customerAlgorithm
+	in.SSECustomerKey = p.customerKey
+	in.SSECustomerKeyMD5 = p.customerKeyMD5
+}
+
+func (p *sseParams) uploadPartCopy(in *s3.UploadPartCopyInput) {
+	if p == nil {
+		return
+	}
+	in.SSECustomerAlgorithm = p.customerAlgorithm
+	in.SSECustomerKey = p.customerKey
+	in.SSECustomerKeyMD5 = p.customerKeyMD5
+	in.CopySourceSSECustomerAlgorithm = p.customerAlgorithm
+	in.CopySourceSSECustomerKey = p.customerKey
+	in.CopySourceSSECustomerKeyMD5 = p.customerKeyMD5
+}
+
+func (p *sseParams) uploadPart(in *s3.UploadPartInput) {
+	if p == nil {
+		return
+	}
+	in.SSECustomerAlgorithm = p.customerAlgorithm
+	in.SSECustomerKey = p.customerKey
+	in.SSECustomerKeyMD5 = p.customerKeyMD5
+}
+
+func (p *sseParams) listParts(in *s3.ListPartsInput) {
+	if p == nil {
+		return
+	}
+	in.SSECustomerAlgorithm = p.customerAlgorithm
+	in.SSECustomerKey = p.customerKey
+	in.SSECustomerKeyMD5 = p.customerKeyMD5
+}
+
+func (p *sseParams) getObject(in *s3.GetObjectInput) {
+	if p == nil {
+		return
+	}
+	in.SSECustomerAlgorithm = p.customerAlgorithm
+	in.SSECustomerKey = p.customerKey
+	in.SSECustomerKeyMD5 = p.customerKeyMD5
+}
+
+func (p *sseParams) headObject(in *s3.HeadObjectInput) {
+	if p == nil {
+		return
+	}
+	in.SSECustomerAlgorithm = p.customerAlgorithm
+	in.SSECustomerKey = p.customerKey
+	in.SSECustomerKeyMD5 = p.customerKeyMD5
+}
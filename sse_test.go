@@ -0,0 +1,72 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestSSEParams(t *testing.T) {
+	t.Run("KMS", func(t *testing.T) {
+		p := newSSEParams(ServerSideEncryption{
+			Type:                 types.ServerSideEncryptionAwsKms,
+			KMSKeyID:             "key-id",
+			KMSEncryptionContext: map[string]string{"foo": "bar"},
+			BucketKeyEnabled:     true,
+		})
+		in := &s3.PutObjectInput{}
+		p.putObject(in)
+		expected := &s3.PutObjectInput{
+			ServerSideEncryption: types.ServerSideEncryptionAwsKms,
+			SSEKMSKeyId:          aws.String("key-id"),
+			// base64 of {"foo":"bar"}
+			SSEKMSEncryptionContext: aws.String("eyJmb28iOiJiYXIifQ=="),
+			BucketKeyEnabled:        aws.Bool(true),
+		}
+		if !reflect.DeepEqual(expected, in) {
+			t.Errorf("Expected %+v, got %+v", expected, in)
+		}
+	})
+	t.Run("CustomerKey", func(t *testing.T) {
+		p := newSSEParams(ServerSideEncryption{CustomerKey: bytes.Repeat([]byte{0}, 32)})
+		in := &s3.CopyObjectInput{}
+		p.copyObject(in)
+		key := "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+		keyMD5 := "cLyPS3KoaSFGi/joRB3OUQ=="
+		expected := &s3.CopyObjectInput{
+			SSECustomerAlgorithm:           aws.String("AES256"),
+			SSECustomerKey:                 &key,
+			SSECustomerKeyMD5:              &keyMD5,
+			CopySourceSSECustomerAlgorithm: aws.String("AES256"),
+			CopySourceSSECustomerKey:       &key,
+			CopySourceSSECustomerKeyMD5:    &keyMD5,
+		}
+		if !reflect.DeepEqual(expected, in) {
+			t.Errorf("Expected %+v, got %+v", expected, in)
+		}
+	})
+	t.Run("Nil", func(t *testing.T) {
+		var p *sseParams
+		in := &s3.GetObjectInput{}
+		p.getObject(in)
+		if !reflect.DeepEqual(&s3.GetObjectInput{}, in) {
+			t.Errorf("Nil params should not modify the input, got %+v", in)
+		}
+	})
+}
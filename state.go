@@ -0,0 +1,402 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// StateMode specifies how the state cache is used.
+type StateMode int
+
+const (
+	// StateTrust lists the dest files from the state cache instead of the dest,
+	// and syncs the source files modified since the last sync.
+	// The dest is listed if the state cache doesn't exist.
+	StateTrust StateMode = iota
+	// StateVerify lists the dest, and logs the files which are recorded in the
+	// state cache but missing or having different size on the dest.
+	// The state cache is rebuilt by the sync.
+	StateVerify
+)
+
+const stateVersion = 1
+
+// stateHeader is the first entry of the state cache file.
+type stateHeader struct {
+	Version int    `json:"version"`
+	Source  string `json:"source"`
+	Dest    string `json:"dest"`
+}
+
+// stateRecord is the state of the source file at the last sync.
+type stateRecord struct {
+	Name         string    `json:"name"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+	ETag         string    `json:"etag,omitempty"`
+}
+
+// syncState is the state cache of the sync.
+// The records are written in order of the names as the files are filtered,
+// and the cache file is replaced on commit.
+// Methods are nil-safe so that they can be called without the state cache.
+type syncState struct {
+	mode     StateMode
+	filename string
+	header   stateHeader
+
+	tmp *os.File
+	zw  *gzip.Writer
+	enc *json.Encoder
+
+	mu sync.Mutex
+	// excluded is the files failed to be synced or deleted from the dest.
+	excluded map[string]bool
+	err      error
+}
+
+// openSyncState creates the state cache of the sync pair.
+func (m *Manager) openSyncState(p *syncPair) (*syncState, error) {
+	if err := os.MkdirAll(m.stateDir, 0755); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(p.dest))
+	s := &syncState{
+		mode:     m.stateMode,
+		filename: filepath.Join(m.stateDir, hex.EncodeToString(sum[:])+".json.gz"),
+		header:   stateHeader{Version: stateVersion, Source: p.source, Dest: p.dest},
+		excluded: make(map[string]bool),
+	}
+	tmp, err := os.CreateTemp(m.stateDir, ".s3sync-state-*")
+	if err != nil {
+		return nil, err
+	}
+	s.tmp = tmp
+	s.zw = gzip.NewWriter(tmp)
+	s.enc = json.NewEncoder(s.zw)
+	if err := s.enc.Encode(&s.header); err != nil {
+		s.close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// open opens the state cache file of the same source and dest.
+// It returns nil if the file doesn't exist or is not compatible.
+func (s *syncState) open(filename string) (*stateReader, error) {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r := &stateReader{Closer: f, dec: json.NewDecoder(zr)}
+	var h stateHeader
+	if err := r.dec.Decode(&h); err != nil {
+		r.Close()
+		return nil, err
+	}
+	if h != s.header {
+		r.Close()
+		return nil, nil
+	}
+	return r, nil
+}
+
+// stateReader reads the records from the state cache file.
+type stateReader struct {
+	io.Closer
+	dec *json.Decoder
+}
+
+// next returns the next record, or nil at the end of the file.
+func (r *stateReader) next() (*stateRecord, error) {
+	var rec stateRecord
+	if err := r.dec.Decode(&rec); err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// trustedFiles returns a channel which receives the dest files recorded in the
+// state cache in StateTrust mode. nil is returned if the state cache is not available.
+func (s *syncState) trustedFiles(ctx context.Context, p *syncPair) (chan *fileInfo, error) {
+	if s == nil || s.mode != StateTrust {
+		return nil, nil
+	}
+	r, err := s.open(s.filename)
+	if r == nil || err != nil {
+		return nil, err
+	}
+
+	c := make(chan *fileInfo, listBufferSize)
+	go func() {
+		defer close(c)
+		defer r.Close()
+		for {
+			rec, err := r.next()
+			if err != nil {
+				sendErrorInfoToChannel(ctx, c, &ListError{Path: s.filename, Err: err})
+				return
+			}
+			if rec == nil {
+				return
+			}
+			fi := &fileInfo{
+				name:         rec.Name,
+				size:         rec.Size,
+				lastModified: rec.LastModified,
+				etag:         rec.ETag,
+			}
+			if p.destS3 != nil {
+				fi.path = path.Join(p.destS3.bucketPrefix, rec.Name)
+			} else {
+				fi.path = filepath.Join(p.dest, filepath.FromSlash(rec.Name))
+			}
+			select {
+			case c <- fi:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return c, nil
+}
+
+// compareWithState requires the source file to be unchanged since the last sync
+// recorded in the state cache.
+func compareWithState(source, recorded *fileInfo) (Reason, error) {
+	switch {
+	case source.size != recorded.size:
+		return ReasonSizeDiffers, nil
+	case source.lastModified.After(recorded.lastModified):
+		return ReasonNewer, nil
+	case source.etag != "" && recorded.etag != "" && source.etag != recorded.etag:
+		return ReasonContentDiffers, nil
+	}
+	return "", nil
+}
+
+// checkStateDrift logs the files recorded in the state cache but missing or
+// having different size on the dest in StateVerify mode.
+// The dest files are passed through as is.
+func (m *Manager) checkStateDrift(ctx context.Context, s *syncState, dest chan *fileInfo) chan *fileInfo {
+	if s == nil || s.mode != StateVerify {
+		return dest
+	}
+	r, err := s.open(s.filename)
+	if r == nil || err != nil {
+		if err != nil {
+			m.warnStateDrift(fmt.Sprintf("failed to read state cache %s: %v", s.filename, err), "")
+		}
+		return dest
+	}
+
+	c := make(chan *fileInfo)
+	go func() {
+		defer close(c)
+		defer r.Close()
+		// Size of the objects differs from the source files if they are
+		// encrypted or compressed.
+		checkSize := !m.usesFileMetadata()
+		var drift int
+		rec, err := r.next()
+		for fi := range dest {
+			for fi.err == nil && err == nil && rec != nil && rec.Name < filepath.ToSlash(fi.name) {
+				m.warnStateDrift("recorded file is missing: "+rec.Name, rec.Name)
+				drift++
+				rec, err = r.next()
+			}
+			if fi.err == nil && err == nil && rec != nil && rec.Name == filepath.ToSlash(fi.name) {
+				if checkSize && rec.Size != fi.size {
+					m.warnStateDrift("recorded file has different size: "+rec.Name, rec.Name)
+					drift++
+				}
+				rec, err = r.next()
+			}
+			select {
+			case c <- fi:
+			case <-ctx.Done():
+				return
+			}
+		}
+		for err == nil && rec != nil {
+			m.warnStateDrift("recorded file is missing: "+rec.Name, rec.Name)
+			drift++
+			rec, err = r.next()
+		}
+		if err != nil {
+			m.warnStateDrift(fmt.Sprintf("failed to read state cache %s: %v", s.filename, err), "")
+		}
+		if drift > 0 {
+			m.log(LogEvent{
+				Level:   slog.LevelWarn,
+				Message: fmt.Sprintf("state cache of %s has drifted from the dest: %d files", s.header.Dest, drift),
+			}, true)
+		}
+	}()
+	return c
+}
+
+func (m *Manager) warnStateDrift(msg, name string) {
+	m.log(LogEvent{Level: slog.LevelWarn, Message: msg, Path: name}, false)
+}
+
+// record records the source file of the operation to be synced or skipped,
+// or the dest file of the deletion, which is excluded once it is deleted so that
+// the failed deletion is retried by the next sync.
+func (s *syncState) record(op *fileOp) {
+	if s == nil || op.err != nil {
+		return
+	}
+	err := s.enc.Encode(&stateRecord{
+		Name:         filepath.ToSlash(op.name),
+		Size:         op.size,
+		LastModified: op.lastModified,
+		ETag:         op.etag,
+	})
+	if err != nil {
+		s.setError(err)
+	}
+}
+
+// fail excludes the file of the failed operation from the state cache.
+// The file failed to be deleted is kept to be deleted by the next sync.
+// The state cache is not committed if the operation is not associated with any file.
+func (s *syncState) fail(op *fileOp) {
+	if s == nil {
+		return
+	}
+	if op.fileInfo == nil || op.name == "" {
+		s.setError(errors.New("file listing failed"))
+		return
+	}
+	if op.op != opDelete {
+		s.exclude(op)
+	}
+}
+
+// complete excludes the deleted file from the state cache.
+func (s *syncState) complete(op *fileOp) {
+	if s != nil && op.op == opDelete {
+		s.exclude(op)
+	}
+}
+
+func (s *syncState) exclude(op *fileOp) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.excluded[filepath.ToSlash(op.name)] = true
+}
+
+func (s *syncState) setError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// commit replaces the state cache file by the records of the sync excluding
+// the failed and the deleted files. Nothing is committed if the sync is not completed.
+func (s *syncState) commit(ctx context.Context) error {
+	if s == nil || s.err != nil || ctx.Err() != nil {
+		return nil
+	}
+	if err := s.zw.Close(); err != nil {
+		return err
+	}
+	if err := s.tmp.Close(); err != nil {
+		return err
+	}
+	if len(s.excluded) > 0 {
+		if err := s.excludeFiles(); err != nil {
+			return err
+		}
+	}
+	return os.Rename(s.tmp.Name(), s.filename)
+}
+
+// excludeFiles rewrites the temporary file excluding the records of the excluded files.
+func (s *syncState) excludeFiles() error {
+	r, err := s.open(s.tmp.Name())
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.filename), ".s3sync-state-*")
+	if err != nil {
+		return err
+	}
+	defer tmp.Close()
+	zw := gzip.NewWriter(tmp)
+	enc := json.NewEncoder(zw)
+	if err := enc.Encode(&s.header); err != nil {
+		return err
+	}
+	for {
+		rec, err := r.next()
+		if err != nil {
+			return err
+		}
+		if rec == nil {
+			break
+		}
+		if s.excluded[rec.Name] {
+			continue
+		}
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	os.Remove(s.tmp.Name())
+	s.tmp = tmp
+	return nil
+}
+
+// close removes the temporary file if it is not committed.
+func (s *syncState) close() {
+	if s == nil {
+		return
+	}
+	s.tmp.Close()
+	os.Remove(s.tmp.Name())
+}
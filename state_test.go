@@ -0,0 +1,218 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/seqsense/s3sync/v2/fakes3"
+)
+
+// warnLogger records the messages of the warning events.
+type warnLogger struct {
+	dummyLogger
+	mu       sync.Mutex
+	warnings []string
+}
+
+func (l *warnLogger) LogEvent(e LogEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e.Level >= slog.LevelWarn {
+		l.warnings = append(l.warnings, e.Message)
+	}
+}
+
+// failingDeleteClient fails to delete the objects.
+type failingDeleteClient struct {
+	*fakes3.Client
+}
+
+func (c *failingDeleteClient) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	return nil, errors.New("delete failed")
+}
+
+func TestStateCache(t *testing.T) {
+	source := t.TempDir()
+	stateDir := filepath.Join(t.TempDir(), "state")
+	past := time.Now().Add(-time.Hour)
+	writeFile := func(t *testing.T, name, data string, mtime time.Time) {
+		t.Helper()
+		filename := filepath.Join(source, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(filename, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"a", "b/c", "d"} {
+		writeFile(t, name, name, past)
+	}
+
+	c := fakes3.New("bucket")
+	run := func(t *testing.T, opts ...Option) *Manager {
+		t.Helper()
+		m := NewWithClient(c, opts...)
+		if err := m.Sync(context.Background(), source, "s3://bucket/dir"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		return m
+	}
+	deleteObject := func(t *testing.T, key string) {
+		t.Helper()
+		_, err := c.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+			Bucket: aws.String("bucket"),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("DryRun", func(t *testing.T) {
+		run(t, WithStateCache(stateDir, StateTrust), WithDryRun())
+		if _, err := os.Stat(stateDir); !os.IsNotExist(err) {
+			t.Errorf("State cache should not be created in dry-run mode: %v", err)
+		}
+	})
+	t.Run("Initial", func(t *testing.T) {
+		if n := run(t, WithStateCache(stateDir, StateTrust)).GetStatistics().Files; n != 3 {
+			t.Errorf("Expected 3 files to be synced, got %d", n)
+		}
+		entries, err := os.ReadDir(stateDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || !strings.HasSuffix(entries[0].Name(), ".json.gz") {
+			t.Errorf("Expected a state cache file, got %v", entries)
+		}
+	})
+	t.Run("Trust", func(t *testing.T) {
+		// The dest is not listed, so the object deleted by others is not synced.
+		deleteObject(t, "dir/a")
+		writeFile(t, "d", "modified", past.Add(time.Minute))
+		if n := run(t, WithStateCache(stateDir, StateTrust)).GetStatistics().Files; n != 1 {
+			t.Errorf("Expected only the modified file to be synced, got %d", n)
+		}
+		expected := []string{"dir/b/c", "dir/d"}
+		if keys := fakeObjectKeys(t, c, "bucket"); !reflect.DeepEqual(expected, keys) {
+			t.Errorf("Expected %v, got %v", expected, keys)
+		}
+	})
+	t.Run("Plan", func(t *testing.T) {
+		writeFile(t, "d", "modified2", past.Add(2*time.Minute))
+		m := NewWithClient(c, WithStateCache(stateDir, StateTrust))
+		plan, err := m.Plan(context.Background(), source, "s3://bucket/dir")
+		if err != nil {
+			t.Fatal("Plan should be successful", err)
+		}
+		// The plan trusts the state cache as Sync does.
+		if len(plan.Operations) != 1 || plan.Operations[0].Dest != "s3://bucket/dir/d" {
+			t.Fatalf("Expected only the modified file to be planned, got %v", plan.Operations)
+		}
+		if err := m.ExecutePlan(context.Background(), plan); err != nil {
+			t.Fatal("ExecutePlan should be successful", err)
+		}
+
+		// The state cache is updated by the executed plan.
+		writeFile(t, "b/c", "modified", past.Add(time.Minute))
+		o := &recordingObserver{}
+		run(t, WithStateCache(stateDir, StateTrust), WithObserver(o))
+		expected := map[string][]string{
+			"s3://bucket/dir/a":   {"skip"},
+			"s3://bucket/dir/b/c": {"planned", "start", "complete"},
+			"s3://bucket/dir/d":   {"skip"},
+		}
+		if !reflect.DeepEqual(expected, o.events) {
+			t.Errorf("Expected events: %v, actual: %v", expected, o.events)
+		}
+	})
+	t.Run("Verify", func(t *testing.T) {
+		l := &warnLogger{}
+		if n := run(t, WithStateCache(stateDir, StateVerify), WithLogger(l)).GetStatistics().Files; n != 1 {
+			t.Errorf("Expected the missing file to be synced, got %d", n)
+		}
+		if len(l.warnings) != 2 || !strings.Contains(l.warnings[0], "missing: a") {
+			t.Errorf("Expected the drift to be logged, got %v", l.warnings)
+		}
+		// The state cache is reconciled.
+		l = &warnLogger{}
+		run(t, WithStateCache(stateDir, StateVerify), WithLogger(l))
+		if len(l.warnings) != 0 {
+			t.Errorf("Expected no drift, got %v", l.warnings)
+		}
+	})
+	t.Run("Delete", func(t *testing.T) {
+		if err := os.Remove(filepath.Join(source, "d")); err != nil {
+			t.Fatal(err)
+		}
+		m := run(t, WithStateCache(stateDir, StateTrust), WithDelete())
+		if n := m.GetStatistics().DeletedFiles; n != 1 {
+			t.Errorf("Expected 1 file to be deleted, got %d", n)
+		}
+		keys := fakeObjectKeys(t, c, "bucket")
+		sort.Strings(keys)
+		expected := []string{"dir/a", "dir/b/c"}
+		if !reflect.DeepEqual(expected, keys) {
+			t.Errorf("Expected %v, got %v", expected, keys)
+		}
+	})
+	t.Run("FailedDelete", func(t *testing.T) {
+		if err := os.Remove(filepath.Join(source, "b", "c")); err != nil {
+			t.Fatal(err)
+		}
+		m := NewWithClient(&failingDeleteClient{Client: c}, WithStateCache(stateDir, StateTrust), WithDelete())
+		if err := m.Sync(context.Background(), source, "s3://bucket/dir"); err == nil {
+			t.Fatal("Sync should fail")
+		}
+
+		// The failed deletion is retried.
+		if n := run(t, WithStateCache(stateDir, StateTrust), WithDelete()).GetStatistics().DeletedFiles; n != 1 {
+			t.Errorf("Expected the file failed to be deleted to be deleted, got %d", n)
+		}
+		if keys := fakeObjectKeys(t, c, "bucket"); !reflect.DeepEqual([]string{"dir/a"}, keys) {
+			t.Errorf("Expected [dir/a], got %v", keys)
+		}
+		// The deleted file is removed from the state cache.
+		if n := run(t, WithStateCache(stateDir, StateTrust), WithDelete()).GetStatistics().DeletedFiles; n != 0 {
+			t.Errorf("Deleted file should not be deleted again, got %d", n)
+		}
+	})
+	t.Run("OtherSource", func(t *testing.T) {
+		// The state cache of the other source is not used.
+		other := t.TempDir()
+		m := NewWithClient(c, WithStateCache(stateDir, StateTrust), WithDelete())
+		if err := m.Sync(context.Background(), other, "s3://bucket/dir"); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+		if keys := fakeObjectKeys(t, c, "bucket"); len(keys) != 0 {
+			t.Errorf("Expected the objects to be deleted, got %v", keys)
+		}
+	})
+}
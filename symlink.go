@@ -0,0 +1,373 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// SymlinkPolicy specifies how the symbolic links in the local directories are synced.
+type SymlinkPolicy int
+
+const (
+	// SymlinksFollow syncs the files and the directories pointed by the symbolic links
+	// as if they are placed at the paths of the links. The links to their parent
+	// directories and the broken links are skipped with warning logs.
+	// It is the default policy.
+	SymlinksFollow SymlinkPolicy = iota
+	// SymlinksSkip ignores the symbolic links.
+	SymlinksSkip
+	// SymlinksPreserve uploads the symbolic links as the objects containing the
+	// link targets, and recreates the links from the objects on download.
+	// The links are compared by the link targets instead of the modification time.
+	// The links to the absolute paths or outside of the dest are not recreated,
+	// and the files are not written through the links in the local dest.
+	SymlinksPreserve
+)
+
+// metaSymlink is the metadata key marking the object as a symbolic link.
+const metaSymlink = "s3sync-symlink"
+
+// maxSymlinkTargetSize is the maximum size of the link target read from the object.
+const maxSymlinkTargetSize = 4096
+
+// walkLocal sends the infos of the files under the dir to the channel in
+// lexicographical order of the slash separated names.
+// ancestors are the directories being walked, which are used to detect the symlink loops.
+func (m *Manager) walkLocal(ctx context.Context, c chan *fileInfo, basePath, dir string, ancestors []os.FileInfo) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	stats := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		stat, err := e.Info()
+		if os.IsNotExist(err) {
+			// Removed during the walk.
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if stat.Mode()&os.ModeSymlink != 0 {
+			switch m.symlinks {
+			case SymlinksSkip:
+				continue
+			case SymlinksPreserve:
+				target, err := os.Readlink(path)
+				if err != nil {
+					return err
+				}
+				stats = append(stats, &symlinkStat{FileInfo: stat, size: int64(len(target))})
+				continue
+			}
+			if stat, err = os.Stat(path); err != nil {
+				m.warnSymlink("skip broken symlink: "+path, path)
+				continue
+			}
+			if stat.IsDir() && isAncestor(stat, ancestors) {
+				m.warnSymlink("skip symlink loop: "+path, path)
+				continue
+			}
+		}
+		stats = append(stats, stat)
+	}
+	sortFileInfos(stats)
+
+	for _, stat := range stats {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		path := filepath.Join(dir, stat.Name())
+		if stat.IsDir() {
+			if err := m.walkLocal(ctx, c, basePath, path, append(ancestors, stat)); err != nil {
+				return err
+			}
+			continue
+		}
+		m.sendFileInfoToChannel(ctx, c, basePath, path, stat, false)
+	}
+	return nil
+}
+
+func isAncestor(dir os.FileInfo, ancestors []os.FileInfo) bool {
+	for _, a := range ancestors {
+		if os.SameFile(dir, a) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Manager) warnSymlink(msg, path string) {
+	m.log(LogEvent{Level: slog.LevelWarn, Message: msg, Path: path}, true)
+}
+
+// symlinkStat is the os.FileInfo of the symbolic link having the length of
+// the link target as its size.
+type symlinkStat struct {
+	os.FileInfo
+	size int64
+}
+
+func (s *symlinkStat) Size() int64 {
+	return s.size
+}
+
+// symlinkComparator wraps the compareFunc to compare the preserved symbolic links
+// by their link targets, since the modification time of the recreated links
+// cannot be set.
+func (m *Manager) symlinkComparator(compare compareFunc) compareFunc {
+	if m.symlinks != SymlinksPreserve {
+		return compare
+	}
+	return func(source, dest *fileInfo) (Reason, error) {
+		if !source.symlink && !dest.symlink {
+			return compare(source, dest)
+		}
+		if source.size != dest.size {
+			return ReasonSizeDiffers, nil
+		}
+		sourceSum, err := symlinkDigest(source)
+		if err != nil {
+			return "", err
+		}
+		destSum, err := symlinkDigest(dest)
+		if err != nil {
+			return "", err
+		}
+		if sourceSum == "" || sourceSum != destSum {
+			return ReasonContentDiffers, nil
+		}
+		return "", nil
+	}
+}
+
+// symlinkDigest returns the hex encoded MD5 digest of the link target of the
+// local symbolic link, or the ETag of the S3 object.
+// Empty string is returned for the local files other than the symbolic links.
+func symlinkDigest(file *fileInfo) (string, error) {
+	if file.etag != "" {
+		return file.etag, nil
+	}
+	if !file.symlink {
+		return "", nil
+	}
+	target, err := os.Readlink(file.path)
+	if err != nil {
+		return "", err
+	}
+	sum := md5.Sum([]byte(target))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// uploadSymlink uploads the link target of the symbolic link.
+// The object is neither compressed nor encrypted.
+func (m *Manager) uploadSymlink(ctx context.Context, file *fileInfo, sourceFilename string, destFile *s3Path, progress func(int64)) error {
+	target, err := os.Readlink(sourceFilename)
+	if err != nil {
+		return err
+	}
+
+	attrs := m.objectAttributes(file.name)
+	if attrs.Metadata == nil {
+		attrs.Metadata = make(map[string]string)
+	}
+	f := *file
+	f.size = int64(len(target))
+	setFileMetadata(attrs.Metadata, &f)
+	attrs.Metadata[metaSymlink] = "true"
+
+	input := &s3.PutObjectInput{
+		Bucket:             &destFile.bucket,
+		Key:                &destFile.bucketPrefix,
+		ACL:                m.acl,
+		Body:               strings.NewReader(target),
+		ContentLength:      aws.Int64(f.size),
+		CacheControl:       attrs.CacheControl,
+		ContentDisposition: attrs.ContentDisposition,
+		ContentLanguage:    attrs.ContentLanguage,
+		Expires:            attrs.Expires,
+		Metadata:           attrs.Metadata,
+		StorageClass:       attrs.StorageClass,
+		Tagging:            attrs.tagging(),
+	}
+	m.sse.putObject(input)
+	if _, err := m.s3.PutObject(ctx, input); err != nil {
+		return err
+	}
+	newProgressTracker(0, progress).add(0, len(target))
+	m.updateFileTransferStatistics(f.size)
+	return nil
+}
+
+// downloadSymlink downloads the link target and recreates the symbolic link.
+func (m *Manager) downloadSymlink(ctx context.Context, file *fileInfo, bucket, key, targetFilename string, progress func(int64)) error {
+	input := &s3.GetObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+	}
+	m.sse.getObject(input)
+	out, err := m.s3.GetObject(ctx, input)
+	if err != nil {
+		return err
+	}
+	defer out.Body.Close()
+	return m.writeSymlink(out.Body, targetFilename, file, progress)
+}
+
+// writeSymlink creates the symbolic link to the target read from r.
+// The links to the absolute paths and the paths outside of the dest are
+// refused, since the files synced later may be written through them.
+func (m *Manager) writeSymlink(r io.Reader, targetFilename string, file *fileInfo, progress func(int64)) error {
+	target, err := io.ReadAll(io.LimitReader(r, maxSymlinkTargetSize))
+	if err != nil {
+		return err
+	}
+	dir := "."
+	if !file.singleFile {
+		dir = filepath.Dir(file.name)
+	}
+	if filepath.IsAbs(string(target)) || !filepath.IsLocal(filepath.Join(dir, string(target))) {
+		return fmt.Errorf("symbolic link target %q is outside of the destination", target)
+	}
+	if err := createSymlink(string(target), targetFilename); err != nil {
+		return err
+	}
+	if err := m.restoreFileAttributes(targetFilename, file); err != nil {
+		return err
+	}
+	newProgressTracker(0, progress).add(0, len(target))
+	m.updateFileTransferStatistics(int64(len(target)))
+	return nil
+}
+
+// copySymlink recreates the symbolic link on the local target.
+func (m *Manager) copySymlink(file *fileInfo, sourceFilename, targetFilename string, progress func(int64)) error {
+	target, err := os.Readlink(sourceFilename)
+	if err != nil {
+		return err
+	}
+	return m.writeSymlink(strings.NewReader(target), targetFilename, file, progress)
+}
+
+// writesSymlinks returns true if the symbolic links may be recreated on the
+// local dest of the pair.
+func (m *Manager) writesSymlinks(p *syncPair) bool {
+	return m.symlinks == SymlinksPreserve && p.destS3 == nil && !isRegisteredBackend(p.destBackend)
+}
+
+// checkSymlinkParents returns an error if any of the parent directories of
+// the file under the root is a symbolic link, so that the files are not written
+// through the links recreated by SymlinksPreserve.
+func checkSymlinkParents(root, filename string) error {
+	rel, err := filepath.Rel(root, filepath.Dir(filename))
+	if err != nil || !filepath.IsLocal(rel) || rel == "." {
+		return nil
+	}
+	dir := root
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		dir = filepath.Join(dir, name)
+		stat, err := os.Lstat(dir)
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if stat.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("parent directory %s is a symbolic link", dir)
+		}
+	}
+	return nil
+}
+
+// checkNestedFiles fails the operations of the source files placed under
+// another source file. Such files can only be listed from S3 and would be
+// written through the symbolic link recreated from the other file.
+// The operations are received in lexicographical order of the names, where
+// the name of a file precedes the names of the files under it.
+func checkNestedFiles(ctx context.Context, ops chan *fileOp) chan *fileOp {
+	c := make(chan *fileOp)
+
+	go func() {
+		defer close(c)
+		// Source file names each of which is a prefix of the next one.
+		var parents []string
+		for op := range ops {
+			if op.err == nil && op.op != opDelete {
+				key := filepath.ToSlash(op.name)
+				for len(parents) > 0 && !strings.HasPrefix(key, parents[len(parents)-1]) {
+					parents = parents[:len(parents)-1]
+				}
+				for _, parent := range parents {
+					if strings.HasPrefix(key, parent+"/") {
+						failed := *op.fileInfo
+						failed.err = fmt.Errorf("file is placed under another file %s", parent)
+						op = &fileOp{fileInfo: &failed}
+						break
+					}
+				}
+				if op.err == nil {
+					parents = append(parents, key)
+				}
+			}
+			select {
+			case c <- op:
+			case <-ctx.Done():
+				// Drain the operations not to block the sender.
+				for range ops {
+				}
+				return
+			}
+		}
+	}()
+
+	return c
+}
+
+// createSymlink creates the symbolic link by renaming the temporary one,
+// so that the existing file is atomically replaced.
+func createSymlink(target, filename string) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	// Reserve the unique name of the temporary link.
+	tmp, err := createTempFile(filename)
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	partialFilename := tmp.Name()
+	defer releaseTempFile(partialFilename)
+	os.Remove(partialFilename)
+	if err := os.Symlink(target, partialFilename); err != nil {
+		return err
+	}
+	if err := os.Rename(partialFilename, filename); err != nil {
+		os.Remove(partialFilename)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2026 SEQSENSE, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package s3sync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// createSymlinkTree creates the directory tree having the symbolic links to a file,
+// a directory, the parent directory and a missing file.
+func createSymlinkTree(t *testing.T) string {
+	t.Helper()
+	temp := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(temp, "dir", "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(temp, "dir", "sub", "foo"), []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"file":         filepath.Join("dir", "sub", "foo"),
+		"linkdir":      filepath.Join("dir", "sub"),
+		"dir/sub/loop": "..",
+		"broken":       "missing",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(temp, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return temp
+}
+
+func listLocalNames(t *testing.T, m *Manager, dir string) []string {
+	t.Helper()
+	var names []string
+	for fi := range m.listLocalFiles(context.Background(), dir) {
+		if fi.err != nil {
+			t.Fatal(fi.err)
+		}
+		names = append(names, filepath.ToSlash(fi.name))
+	}
+	return names
+}
+
+func TestSymlinks(t *testing.T) {
+	t.Run("Follow", func(t *testing.T) {
+		temp := createSymlinkTree(t)
+		names := listLocalNames(t, NewWithClient(nil), temp)
+		expected := []string{"dir/sub/foo", "file", "linkdir/foo", "linkdir/loop/sub/foo"}
+		if !reflect.DeepEqual(expected, names) {
+			t.Errorf("Expected %v, got %v", expected, names)
+		}
+	})
+	t.Run("Skip", func(t *testing.T) {
+		temp := createSymlinkTree(t)
+		names := listLocalNames(t, NewWithClient(nil, WithSymlinks(SymlinksSkip)), temp)
+		expected := []string{"dir/sub/foo"}
+		if !reflect.DeepEqual(expected, names) {
+			t.Errorf("Expected %v, got %v", expected, names)
+		}
+	})
+	t.Run("Preserve", func(t *testing.T) {
+		temp := createSymlinkTree(t)
+		dest := t.TempDir()
+		m := NewWithClient(nil, WithSymlinks(SymlinksPreserve))
+		if err := m.Sync(context.Background(), temp, dest); err != nil {
+			t.Fatal("Sync should be successful", err)
+		}
+
+		var links []string
+		err := filepath.Walk(dest, func(path string, stat os.FileInfo, err error) error {
+			if err != nil || stat.Mode()&os.ModeSymlink == 0 {
+				return err
+			}
+			rel, _ := filepath.Rel(dest, path)
+			target, err := os.Readlink(path)
+			links = append(links, filepath.ToSlash(rel)+" -> "+filepath.ToSlash(target))
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(links)
+		expected := []string{
+			"broken -> missing",
+			"dir/sub/loop -> ..",
+			"file -> dir/sub/foo",
+			"linkdir -> dir/sub",
+		}
+		if !reflect.DeepEqual(expected, links) {
+			t.Errorf("Expected %v, got %v", expected, links)
+		}
+	})
+}
+
+func TestCheckNestedFilesCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ops := make(chan *fileOp)
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		defer close(ops)
+		for _, name := range []string{"a", "b", "c"} {
+			ops <- &fileOp{fileInfo: &fileInfo{name: name}}
+		}
+	}()
+
+	c := checkNestedFiles(ctx, ops)
+	<-c
+	cancel()
+
+	// The sender must not be blocked after the cancellation.
+	select {
+	case <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Operations should be drained on cancellation")
+	}
+}
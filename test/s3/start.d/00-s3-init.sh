@@ -16,6 +16,14 @@ awslocal s3 mb s3://s3-destination
 
 awslocal s3 mb s3://s3-destination2
 
+awslocal s3 mb s3://s3-destination-multipart
+
+awslocal s3 mb s3://s3-destination-delete
+awslocal s3 cp /fixture/README.md s3://s3-destination-delete/dest_only_file
+
+awslocal s3 mb s3://s3-destination-dryrun
+awslocal s3 cp /fixture/README.md s3://s3-destination-dryrun/dest_only_file
+
 awslocal s3 mb s3://example-bucket-escaped
 
 awslocal s3 mb s3://example-bucket-upload
@@ -37,3 +45,5 @@ awslocal s3 mb s3://example-bucket-directory
 awslocal s3api put-object --bucket example-bucket-directory --key test/
 
 awslocal s3 mb s3://example-bucket-mime
+
+awslocal s3 mb s3://example-bucket-checksum